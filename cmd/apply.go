@@ -0,0 +1,93 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/plan"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply plan.json",
+	Short: "Apply a plan saved with 'ime push --out'",
+	Long: "Applies exactly the changeset saved by 'ime push --out'. The plan is refused if the parameters " +
+		"in the AWS Parameter Store changed after it was made.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		p, err := plan.Load(args[0])
+		if err != nil {
+			fmt.Printf("Error loading plan: %s \n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		envConf, err := cfg.GetEnvironment(p.Project, p.Env)
+		if err != nil {
+			fmt.Printf("Error getting environment from config ime.yaml: %s \n", err)
+			os.Exit(1)
+		}
+
+		ef := environment.NewEnvFileFromPath(envConf.GetResolvedLocalPath())
+		if p.Mode == plan.ModeMerge {
+			if err := ef.LoadEnvFile(); err != nil {
+				fmt.Printf("Error loading environment file: %s \n", err)
+				os.Exit(1)
+			}
+		}
+
+		ctx, cancel, err := commandContext(cfg, p.Project, p.Env)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, p.Project, p.Env, paramstore.Filter{})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		types, err := parameterTypes(cfg, p.Project, p.Env, ef)
+		if err != nil {
+			fmt.Printf("Error getting parameter types: %s \n", err)
+			os.Exit(1)
+		}
+
+		listFormat, err := cfg.GetStringListFormat(p.Project, p.Env)
+		if err != nil {
+			fmt.Printf("Error getting string list format: %s \n", err)
+			os.Exit(1)
+		}
+
+		refs := newResolver(cfg, p.Project, p.Env, ps)
+		if err := CheckPlan(ctx, ps, refs, ef, p, types); err != nil {
+			fmt.Printf("Refusing to apply plan: %s \n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Applying plan to %s (%s) \n", ps.Path(), p.Mode)
+		if err := ApplyPlan(ctx, ps, refs, ef, p, listFormat); err != nil {
+			fmt.Printf("Error applying plan: %s \n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+)
+
+// Tags ime puts on every parameter it writes, on top of the tags in ime.yaml.
+const (
+	managedByTag = "managed-by"
+	projectTag   = "ime:project"
+	envTag       = "ime:env"
+)
+
+// commandContext returns the context a command talks to the backend with. It
+// is cancelled by Ctrl-C or SIGTERM, and expires after --timeout or, without
+// the flag, the timeout ime.yaml sets for the environment.
+func commandContext(cfg *config.Config, projectName, environmentName string) (context.Context, context.CancelFunc, error) {
+	timeouts, err := cfg.GetTimeouts(projectName, environmentName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	total := timeouts.Total
+	if timeoutFlag > 0 {
+		total = timeoutFlag
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if total <= 0 {
+		return ctx, stop, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, total)
+	return ctx, func() {
+		cancel()
+		stop()
+	}, nil
+}
+
+// newBackend returns the backend ime.yaml configures for the environment,
+// rooted at the environment's parameter store path. filter narrows what the
+// backend reads.
+func newBackend(ctx context.Context, cfg *config.Config, projectName, environmentName string, filter paramstore.Filter) (paramstore.Backend, error) {
+	kind, opts, err := backendOptions(cfg, projectName, environmentName)
+	if err != nil {
+		return nil, err
+	}
+	opts.Filter = filter
+	return paramstore.NewBackend(ctx, kind, opts)
+}
+
+// backendOptions returns the kind of backend ime.yaml configures for the
+// environment and the options to build it with.
+func backendOptions(cfg *config.Config, projectName, environmentName string) (string, paramstore.Options, error) {
+	path, err := cfg.FormatParameterStorePath(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	kind, err := cfg.GetBackend(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	mapping, err := cfg.GetKeyMapping(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	kmsKeyID, err := cfg.GetKMSKeyID(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	tier, err := cfg.GetTier(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	timeouts, err := cfg.GetTimeouts(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	awsSettings, err := cfg.GetAWS(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	policies, err := cfg.GetPolicies(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+
+	tags, err := cfg.GetTags(projectName, environmentName)
+	if err != nil {
+		return "", paramstore.Options{}, err
+	}
+	tags[managedByTag] = "ime"
+	tags[projectTag] = projectName
+	tags[envTag] = environmentName
+
+	return kind, paramstore.Options{
+		Path: path,
+		KeyMapping: paramstore.KeyMapping{
+			Separator: mapping.Separator,
+			Case:      mapping.Case,
+		},
+		KMSKeyID:    kmsKeyID,
+		Tier:        tier,
+		Tags:        tags,
+		Policies:    keyPolicies(policies),
+		CallTimeout: timeouts.Call,
+		AWS: paramstore.AWSOptions{
+			Region:      awsSettings.Region,
+			Profile:     awsSettings.Profile,
+			RoleARN:     awsSettings.RoleARN,
+			ExternalID:  awsSettings.ExternalID,
+			SessionName: awsSettings.SessionName,
+			EndpointURL: awsSettings.EndpointURL,
+		},
+	}, nil
+}
+
+// keyPolicies converts the policies ime.yaml sets into the backend's form.
+func keyPolicies(policies config.ParameterPolicies) paramstore.KeyPolicies {
+	convert := func(p config.Policies) paramstore.Policies {
+		return paramstore.Policies{
+			ExpiresAfter:         p.ExpiresAfter,
+			NotifyBeforeExpiry:   p.NotifyBeforeExpiry,
+			NotifyIfUnchangedFor: p.NotifyIfUnchangedFor,
+		}
+	}
+
+	converted := paramstore.KeyPolicies{
+		Default: convert(policies.Default),
+		Keys:    make(map[string]paramstore.Policies, len(policies.Keys)),
+	}
+	for k, p := range policies.Keys {
+		converted.Keys[k] = convert(p)
+	}
+	return converted
+}
+
+// requestedKeys returns the keys passed with --keys or, without the flag, the
+// keys list ime.yaml sets for the environment. Empty means every parameter.
+func requestedKeys(cfg *config.Config, projectName, environmentName string, flagKeys []string) ([]string, error) {
+	if len(flagKeys) > 0 {
+		return flagKeys, nil
+	}
+	return cfg.GetKeys(projectName, environmentName)
+}
+
+// readParameters returns the given keys, fetched by name, or every parameter
+// under the path when keys is empty. Asking for keys that do not exist is an
+// error naming each of them.
+func readParameters(ctx context.Context, ps paramstore.Backend, keys []string) (map[string]string, error) {
+	if len(keys) == 0 {
+		return ps.GetParameters(ctx)
+	}
+
+	params, missing, err := ps.GetParametersByName(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%d requested key(s) not found under %s: %s", len(missing), ps.Path(), strings.Join(missing, ", "))
+	}
+	return params, nil
+}
+
+// parseTags turns --tag k=v flags into a map.
+func parseTags(flags []string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, f := range flags {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("tag %q must be written as key=value", f)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// parameterTypes returns the parameter types ime.yaml sets for the environment.
+// An "# ime:type=..." annotation in the env file wins over ime.yaml.
+func parameterTypes(cfg *config.Config, projectName, environmentName string, ef *environment.EnvFile) (config.ParameterTypes, error) {
+	types, err := cfg.GetParameterTypes(projectName, environmentName)
+	if err != nil {
+		return types, err
+	}
+
+	for _, key := range ef.Keys() {
+		paramType, ok := ef.Annotation(key, "type")
+		if !ok {
+			continue
+		}
+		if err := config.ValidateParameterType(paramType); err != nil {
+			return types, fmt.Errorf("%s: %s: %w", ef.Path, key, err)
+		}
+		types.Keys[key] = paramType
+	}
+	return types, nil
+}
+
+// normalizeStringLists rewrites StringList values held in the env file as JSON
+// arrays into the comma-joined form the backend stores, so the two compare
+// equal. It must run after the backend has been read.
+func normalizeStringLists(ps paramstore.Backend, ef *environment.EnvFile, types config.ParameterTypes) {
+	remote := ps.ParameterTypes()
+	for k, v := range ef.Vars {
+		if types.TypeOf(k) == paramstore.TypeStringList || remote[k] == paramstore.TypeStringList {
+			ef.Vars[k] = paramstore.NormalizeStringList(v)
+		}
+	}
+}
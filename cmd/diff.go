@@ -0,0 +1,167 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/reference"
+	"github.com/spf13/cobra"
+)
+
+// diffDriftExitCode is returned when the env file and the parameter store differ,
+// so scripts can tell drift apart from an error.
+const diffDriftExitCode = 2
+
+var showValuesFlag bool
+
+// DiffEntry is a key whose value differs between the env file and the
+// parameter store. Status is added when only the env file has the key,
+// removed when only the parameter store has it, and changed otherwise.
+type DiffEntry struct {
+	Key    string
+	Status string
+	Local  string
+	Remote string
+}
+
+func DiffParameters(ctx context.Context, ps paramstore.Backend, refs *reference.Resolver, ef *environment.EnvFile, types config.ParameterTypes) ([]DiffEntry, error) {
+	remote, err := ps.GetParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	normalizeStringLists(ps, ef, types)
+	normalizeReferences(ctx, refs, ef, remote)
+
+	var entries []DiffEntry
+
+	for key, localValue := range ef.Vars {
+		remoteValue, inRemote := remote[key]
+		switch {
+		case !inRemote:
+			entries = append(entries, DiffEntry{Key: key, Status: "added", Local: localValue})
+		case localValue != remoteValue:
+			entries = append(entries, DiffEntry{Key: key, Status: "changed", Local: localValue, Remote: remoteValue})
+		}
+	}
+
+	for key, remoteValue := range remote {
+		if _, inLocal := ef.Vars[key]; !inLocal {
+			entries = append(entries, DiffEntry{Key: key, Status: "removed", Remote: remoteValue})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// PrintDiff prints the entries as a table. Unless showValues is set, values are
+// replaced by the first characters of their hash.
+func PrintDiff(entries []DiffEntry, showValues bool) {
+	display := func(value string, present bool) string {
+		switch {
+		case !present:
+			return "-"
+		case showValues:
+			return value
+		default:
+			return environment.HashValue(value)[:8]
+		}
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Key", "Status", "Local", "Remote"})
+
+	for _, e := range entries {
+		table.Append([]string{
+			e.Key,
+			e.Status,
+			display(e.Local, e.Status != "removed"),
+			display(e.Remote, e.Status != "added"),
+		})
+	}
+
+	table.Render()
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the differences between an env file and the AWS Parameter Store",
+	Long: fmt.Sprintf("Compares the environment's local env file with the AWS Parameter Store. "+
+		"Values are shown as short hashes unless --show-values is passed. Exits with %d when they differ.", diffDriftExitCode),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		envConf, err := cfg.GetEnvironment(projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting environment from config ime.yaml: %s \n", err)
+			os.Exit(1)
+		}
+
+		ef := environment.NewEnvFileFromPath(envConf.GetResolvedLocalPath())
+		if err := ef.LoadEnvFile(); err != nil {
+			fmt.Printf("Error loading environment file: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		types, err := parameterTypes(cfg, projFlag, envFlag, ef)
+		if err != nil {
+			fmt.Printf("Error getting parameter types: %s \n", err)
+			os.Exit(1)
+		}
+
+		entries, err := DiffParameters(ctx, ps, newResolver(cfg, projFlag, envFlag, ps), ef, types)
+		if err != nil {
+			fmt.Printf("Error comparing parameters: %s \n", err)
+			os.Exit(1)
+		}
+
+		if len(entries) == 0 {
+			fmt.Printf("%s matches %s \n", ef.Path, ps.Path())
+			os.Exit(0)
+		}
+
+		PrintDiff(entries, showValuesFlag)
+		os.Exit(diffDriftExitCode)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&projFlag, "project", "", "The project to compare")
+	diffCmd.Flags().StringVar(&envFlag, "env", "", "The environment to compare")
+	diffCmd.Flags().BoolVar(&showValuesFlag, "show-values", false, "Show values instead of short hashes")
+
+	// Mark the required flags
+	diffCmd.MarkFlagRequired("project")
+	diffCmd.MarkFlagRequired("env")
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+)
+
+func TestDiffParameters(t *testing.T) {
+	ps, _ := newTestBackend(t, map[string]string{"SAME": "1", "CHANGED": "old", "REMOVED": "x"})
+	ef := newTestEnvFile(t, "SAME=1\nCHANGED=new\nADDED=y\n")
+
+	entries, err := DiffParameters(context.Background(), ps, newTestResolver(ps), ef, config.ParameterTypes{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []DiffEntry{
+		{Key: "ADDED", Status: "added", Local: "y"},
+		{Key: "CHANGED", Status: "changed", Local: "new", Remote: "old"},
+		{Key: "REMOVED", Status: "removed", Remote: "x"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, but got %+v", len(expected), entries)
+	}
+	for i, e := range expected {
+		if entries[i] != e {
+			t.Errorf("expected %+v, but got %+v", e, entries[i])
+		}
+	}
+}
@@ -1,95 +1,180 @@
-// /*
-// Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
-// */
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
 package cmd
 
-// import (
-// 	"fmt"
-// 	"os"
-
-// 	"github.com/pytoolbelt/ime/pkg/config"
-// 	"github.com/pytoolbelt/ime/pkg/environment"
-// 	"github.com/pytoolbelt/ime/pkg/paramstore"
-// 	"github.com/spf13/cobra"
-// )
-
-// // fetchCmd represents the fetch command
-// var fetchCmd = &cobra.Command{
-// 	Use:   "fetch",
-// 	Short: "fetch an environment from the AWS Parameter Store",
-// 	Long:  "fetches an environment from the AWS Parameter Store and saves it in the .env file.",
-// 	Run: func(cmd *cobra.Command, args []string) {
-
-// 		cfg, err := config.LoadConfig()
-// 		if err != nil {
-// 			fmt.Printf("Error loading configuration: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		projectName, err := cmd.Flags().GetString("project")
-// 		if err != nil {
-// 			fmt.Printf("Error getting project: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		environmentName, err := cmd.Flags().GetString("env")
-// 		if err != nil {
-// 			fmt.Printf("Error getting environment: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		path, err := cfg.FormatParameterStorePath(projectName, environmentName)
-// 		if err != nil {
-// 			fmt.Printf("Error formatting parameter store path: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		env, err := cfg.GetEnvironment(projectName, environmentName)
-// 		if err != nil {
-// 			fmt.Printf("Error getting environment: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		fmt.Printf("Fetching %s from %s \n", environmentName, path)
-
-// 		result, err := paramstore.GetParametersByPath(path)
-
-// 		if err != nil {
-// 			fmt.Printf("Error fetching parameters: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		params, err := paramstore.ParseParameterKeyValuePairs(*result)
-
-// 		if err != nil {
-// 			fmt.Printf("Error parsing parameters: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		ef := environment.NewEnvFileFromPath(env.GetResolvedLocalPath())
-// 		if err := ef.LoadEnvFile(); err != nil {
-// 			fmt.Printf("Error loading environment file: %s \n", err)
-// 			os.Exit(1)
-// 		}
-
-// 		ef.ConvertEnvVarsToMap(params)
-
-// 		if err := ef.WriteEnvFile(); err != nil {
-// 			fmt.Printf("Error writing environment file: %s \n", err)
-// 			os.Exit(1)
-// 		}
-// 	},
-// }
-
-// func init() {
-// 	rootCmd.AddCommand(fetchCmd)
-// 	fetchCmd.Flags().String("project", "", "The project to fetch")
-// 	fetchCmd.Flags().String("env", "", "The project environment to fetch")
-
-// 	// Mark the required flags
-// 	fetchCmd.MarkFlagRequired("project")
-// 	fetchCmd.MarkFlagRequired("env")
-
-// 	// init viper config
-// 	config.InitializeConfig()
-// }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/reference"
+	"github.com/spf13/cobra"
+)
+
+// FetchParameters writes the values from the parameter store into the env file,
+// only the given keys or every parameter when keys is empty. Keys that only
+// exist locally are kept, and so are comments, blank lines and the order of keys
+// already in the file. New keys are appended in sorted order. StringList values
+// are written in listFormat, csv or json. References in values are written
+// resolved, while the baseline keeps them as stored.
+func FetchParameters(ctx context.Context, ps paramstore.Backend, refs *reference.Resolver, ef *environment.EnvFile, keys []string, listFormat string) error {
+	params, err := readParameters(ctx, ps, keys)
+	if err != nil {
+		return err
+	}
+	resolved, err := refs.ResolveAll(ctx, params)
+	if err != nil {
+		return err
+	}
+	formatted := paramstore.FormatStringLists(ps, resolved, listFormat)
+
+	fetched := make([]string, 0, len(params))
+	for k := range params {
+		fetched = append(fetched, k)
+	}
+	sort.Strings(fetched)
+
+	for _, k := range fetched {
+		ef.Set(k, formatted[k])
+	}
+
+	if err := ef.WriteEnvFile(); err != nil {
+		return err
+	}
+
+	// The env file now matches the parameter store for every fetched key.
+	base := environment.NewBaselineForEnvFile(ef)
+	if err := base.Load(); err != nil {
+		return err
+	}
+	for _, k := range fetched {
+		base.Set(k, params[k])
+	}
+	if err := base.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Fetched %d parameters into %s \n", len(fetched), ef.Path)
+	return nil
+}
+
+// fetchCmd represents the fetch command
+var fetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "fetch an environment from the AWS Parameter Store",
+	Long:  "fetches an environment from the AWS Parameter Store and saves it in the .env file.",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		projectName, err := cmd.Flags().GetString("project")
+		if err != nil {
+			fmt.Printf("Error getting project: %s \n", err)
+			os.Exit(1)
+		}
+
+		environmentName, err := cmd.Flags().GetString("env")
+		if err != nil {
+			fmt.Printf("Error getting environment: %s \n", err)
+			os.Exit(1)
+		}
+
+		env, err := cfg.GetEnvironment(projectName, environmentName)
+		if err != nil {
+			fmt.Printf("Error getting environment: %s \n", err)
+			os.Exit(1)
+		}
+
+		tagFilters, err := cmd.Flags().GetStringArray("tag")
+		if err != nil {
+			fmt.Printf("Error getting tags: %s \n", err)
+			os.Exit(1)
+		}
+
+		tags, err := parseTags(tagFilters)
+		if err != nil {
+			fmt.Printf("Error parsing tags: %s \n", err)
+			os.Exit(1)
+		}
+
+		flagKeys, err := cmd.Flags().GetStringSlice("keys")
+		if err != nil {
+			fmt.Printf("Error getting keys: %s \n", err)
+			os.Exit(1)
+		}
+
+		keys, err := requestedKeys(cfg, projectName, environmentName, flagKeys)
+		if err != nil {
+			fmt.Printf("Error getting keys: %s \n", err)
+			os.Exit(1)
+		}
+		if len(keys) > 0 && len(tags) > 0 {
+			fmt.Printf("Error: --tag can't be combined with a list of keys \n")
+			os.Exit(1)
+		}
+
+		label, err := cmd.Flags().GetString("label")
+		if err != nil {
+			fmt.Printf("Error getting label: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projectName, environmentName)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projectName, environmentName, paramstore.Filter{Tags: tags, Label: label})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Fetching %s from %s \n", environmentName, ps.Path())
+
+		// A missing env file is fine, fetch creates it.
+		ef := environment.NewEnvFileFromPath(env.GetResolvedLocalPath())
+		if err := ef.LoadEnvFile(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("Error loading environment file: %s \n", err)
+			os.Exit(1)
+		}
+
+		listFormat, err := cfg.GetStringListFormat(projectName, environmentName)
+		if err != nil {
+			fmt.Printf("Error getting string list format: %s \n", err)
+			os.Exit(1)
+		}
+
+		refs := newResolver(cfg, projectName, environmentName, ps)
+		if err := FetchParameters(ctx, ps, refs, ef, keys, listFormat); err != nil {
+			fmt.Printf("Error fetching parameters: %s \n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fetchCmd)
+	fetchCmd.Flags().String("project", "", "The project to fetch")
+	fetchCmd.Flags().String("env", "", "The project environment to fetch")
+	fetchCmd.Flags().StringArray("tag", nil, "Only fetch parameters with this tag, as key=value. Can be repeated")
+	fetchCmd.Flags().StringSlice("keys", nil, "Only fetch these keys, e.g. A,B,C. Overrides keys in ime.yaml")
+	fetchCmd.Flags().String("label", "", "Fetch the versions carrying this label instead of the latest")
+
+	// Mark the required flags
+	fetchCmd.MarkFlagRequired("project")
+	fetchCmd.MarkFlagRequired("env")
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/paramstore/fakesecrets"
+	"github.com/pytoolbelt/ime/pkg/plan"
+	"github.com/pytoolbelt/ime/pkg/reference"
+)
+
+func TestFetchParameters(t *testing.T) {
+	ps, _ := newTestBackend(t, map[string]string{"DB_HOST": "db.internal", "NEW_KEY": "new value"})
+	ef := newTestEnvFile(t, "# database\nDB_HOST=localhost\n\nLOCAL_ONLY=1\n")
+
+	if err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, nil, "csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(ef.Path)
+	if err != nil {
+		t.Fatalf("failed to read env file: %v", err)
+	}
+
+	expected := "# database\nDB_HOST=db.internal\n\nLOCAL_ONLY=1\nNEW_KEY=\"new value\"\n"
+	if string(data) != expected {
+		t.Errorf("expected file:\n%s\nbut got:\n%s", expected, string(data))
+	}
+}
+
+func TestFetchStringListAsJSON(t *testing.T) {
+	ps, _ := newTestBackend(t, nil)
+	if _, err := ps.PutParameter(context.Background(), paramstore.Parameter{Key: "HOSTS", Value: "a,b", Type: "StringList"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	ef := newTestEnvFile(t, "")
+
+	if err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, nil, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ef.Vars["HOSTS"] != `["a","b"]` {
+		t.Errorf("expected HOSTS as a JSON array, but got %q", ef.Vars["HOSTS"])
+	}
+
+	// The JSON form still matches the comma-joined value in the parameter store.
+	entries, err := DiffParameters(context.Background(), ps, newTestResolver(ps), ef, config.ParameterTypes{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no differences, but got %+v", entries)
+	}
+}
+
+func TestFetchKeys(t *testing.T) {
+	remote := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		remote[fmt.Sprintf("KEY_%03d", i)] = "x"
+	}
+	ps, client := newTestBackend(t, remote)
+	ef := newTestEnvFile(t, "")
+
+	if err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, []string{"KEY_007", "KEY_123"}, "csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ef.Vars) != 2 || ef.Vars["KEY_007"] != "x" || ef.Vars["KEY_123"] != "x" {
+		t.Errorf("expected only KEY_007 and KEY_123, but got %v", ef.Vars)
+	}
+	if client.Calls["GetParametersByPath"] != 0 || client.Calls["GetParameters"] != 1 {
+		t.Errorf("expected a single GetParameters call, but got %v", client.Calls)
+	}
+
+	err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, []string{"KEY_001", "MISSING_B", "MISSING_A"}, "csv")
+	if err == nil || !strings.Contains(err.Error(), "MISSING_A, MISSING_B") {
+		t.Errorf("expected an error naming MISSING_A and MISSING_B, but got %v", err)
+	}
+}
+
+func TestFetchResolvesReferences(t *testing.T) {
+	resetPushFlags(t)
+	remote := map[string]string{
+		"DB_USER":      "app",
+		"DATABASE_URL": "postgres://{{ime:/global/project1/dev/DB_USER}}@db",
+		"USER_ALIAS":   "{{ime:project1/dev/DB_USER}}",
+	}
+	ps, _ := newTestBackend(t, remote)
+	ef := newTestEnvFile(t, "")
+
+	if err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, nil, "csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ef.Vars["DATABASE_URL"] != "postgres://app@db" || ef.Vars["USER_ALIAS"] != "app" {
+		t.Errorf("expected resolved references, but got %v", ef.Vars)
+	}
+
+	// Pushing the fetched file back keeps the references.
+	overwriteFlag = true
+	if err := push(t, ps, ef, plan.ModeAdd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, remote)
+}
+
+func TestFetchDanglingReference(t *testing.T) {
+	ps, _ := newTestBackend(t, map[string]string{"API_KEY": "{{ime:project1/dev/RETIRED_KEY}}"})
+	ef := newTestEnvFile(t, "")
+
+	err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, nil, "csv")
+	if !errors.Is(err, reference.ErrNotFound) || !strings.Contains(err.Error(), "API_KEY") {
+		t.Errorf("expected a dangling reference error naming API_KEY, but got %v", err)
+	}
+}
+
+func TestFetchUnknownLabel(t *testing.T) {
+	ps, _ := newTestBackend(t, map[string]string{"A": "1"})
+	ps.Filter = paramstore.Filter{Label: "release-typo"}
+	ef := newTestEnvFile(t, "A=local\n")
+
+	err := FetchParameters(context.Background(), ps, newTestResolver(ps), ef, nil, "csv")
+	if err == nil || !strings.Contains(err.Error(), "release-typo") {
+		t.Errorf("expected an error naming the label, but got %v", err)
+	}
+	if ef.Vars["A"] != "local" {
+		t.Errorf("expected the env file to be left alone, but got %v", ef.Vars)
+	}
+}
+
+func TestFetchSecretsManagerKeyMapping(t *testing.T) {
+	client := fakesecrets.New()
+	client.Seed(testPath, `{"db-host":"x","db.port":5432}`)
+	ss := paramstore.NewSecretStoreWithClient(client, testPath)
+	ss.KeyMapping = paramstore.KeyMapping{Case: "upper"}
+	ef := newTestEnvFile(t, "")
+
+	if err := FetchParameters(context.Background(), ss, newTestResolver(ss), ef, nil, "csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The fetched file loads again, so push and diff can use it.
+	reloaded := environment.NewEnvFileFromPath(ef.Path)
+	if err := reloaded.LoadEnvFile(); err != nil {
+		t.Fatalf("unexpected error loading the fetched file: %v", err)
+	}
+	if reloaded.Vars["DB_HOST"] != "x" || reloaded.Vars["DB_PORT"] != "5432" {
+		t.Errorf("expected DB_HOST and DB_PORT, but got %v", reloaded.Vars)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/spf13/cobra"
+)
+
+// findParameter looks key up so it resolves to the name it is stored under,
+// and fails when the environment has no such key.
+func findParameter(ctx context.Context, ps paramstore.Backend, key string) error {
+	_, missing, err := ps.GetParametersByName(ctx, []string{key})
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("parameter %s not found under %s", key, ps.Path())
+	}
+	return nil
+}
+
+// PrintHistory prints every version of a parameter, oldest first. Values are
+// replaced by the first characters of their hash.
+func PrintHistory(versions []paramstore.ParameterVersion) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Version", "Last Modified", "Modified By", "Value"})
+
+	for _, v := range versions {
+		table.Append([]string{
+			strconv.FormatInt(v.Version, 10),
+			v.LastModified.Format("2006-01-02 15:04:05 MST"),
+			v.ModifiedBy,
+			environment.HashValue(v.Value)[:8],
+		})
+	}
+
+	table.Render()
+}
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history KEY",
+	Short: "Show the version history of a parameter",
+	Long: "Shows every version of a parameter in the AWS Parameter Store with the date and user of the change. " +
+		"Values are shown as short hashes, use 'ime rollback' to restore one.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		if err := findParameter(ctx, ps, key); err != nil {
+			fmt.Printf("Error finding parameter: %s \n", err)
+			os.Exit(1)
+		}
+
+		versions, err := ps.GetParameterHistory(ctx, key)
+		if err != nil {
+			fmt.Printf("Error getting history: %s \n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("History of %s \n", ps.FormatParamName(key))
+		PrintHistory(versions)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVar(&projFlag, "project", "", "The project of the parameter")
+	historyCmd.Flags().StringVar(&envFlag, "env", "", "The environment of the parameter")
+
+	// Mark the required flags
+	historyCmd.MarkFlagRequired("project")
+	historyCmd.MarkFlagRequired("env")
+}
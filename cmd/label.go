@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/spf13/cobra"
+)
+
+var labelFlag string
+
+// LabelParameters attaches label to the current version of every parameter
+// of the environment. It returns the keys that were labeled and, for every key
+// that was not, the reason why.
+func LabelParameters(ctx context.Context, ps paramstore.Backend, label string) ([]string, map[string]error, error) {
+	params, err := ps.GetParameters(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var labeled []string
+	failed := make(map[string]error)
+	for _, k := range keys {
+		if err := ps.LabelParameter(ctx, k, label); err != nil {
+			failed[k] = err
+			continue
+		}
+		labeled = append(labeled, k)
+	}
+	return labeled, failed, nil
+}
+
+// labelCmd represents the label command
+var labelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Label the current version of every parameter of an environment",
+	Long: "Attaches a label to the current version of every parameter of an environment in the AWS Parameter Store. " +
+		"Pass the label to 'ime fetch' or 'ime run' to use those versions later. A label already on an older version is moved.",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		labeled, failed, err := LabelParameters(ctx, ps, labelFlag)
+		if err != nil {
+			fmt.Printf("Error labeling parameters: %s \n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Labeled %d parameters under %s as %s, Failed: %d \n", len(labeled), ps.Path(), labelFlag, len(failed))
+
+		failedKeys := make([]string, 0, len(failed))
+		for k := range failed {
+			failedKeys = append(failedKeys, k)
+		}
+		sort.Strings(failedKeys)
+		for _, k := range failedKeys {
+			fmt.Printf("  failed %s: %s \n", k, failed[k])
+		}
+
+		if len(failed) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(labelCmd)
+	labelCmd.Flags().StringVar(&projFlag, "project", "", "The project to label")
+	labelCmd.Flags().StringVar(&envFlag, "env", "", "The environment to label")
+	labelCmd.Flags().StringVar(&labelFlag, "label", "", "The label to attach, e.g. release-42")
+
+	// Mark the required flags
+	labelCmd.MarkFlagRequired("project")
+	labelCmd.MarkFlagRequired("env")
+	labelCmd.MarkFlagRequired("label")
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+)
+
+func TestLabelParameters(t *testing.T) {
+	ps, _ := newTestBackend(t, map[string]string{"A": "a1", "B": "b1"})
+
+	labeled, failed, err := LabelParameters(context.Background(), ps, "release-42")
+	if err != nil {
+		t.Fatalf("unexpected error labeling: %v", err)
+	}
+	if len(labeled) != 2 || len(failed) != 0 {
+		t.Fatalf("expected 2 labeled and none failed, but got %v and %v", labeled, failed)
+	}
+
+	// Later pushes do not change what the label points at.
+	if _, err := ps.PutParameter(context.Background(), paramstore.Parameter{Key: "A", Value: "a2"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+
+	pinned := paramstore.NewParamStoreWithClient(ps.SSMClient, testPath)
+	pinned.Filter = paramstore.Filter{Label: "release-42"}
+	params, err := pinned.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting labeled parameters: %v", err)
+	}
+	if params["A"] != "a1" || params["B"] != "b1" || len(params) != 2 {
+		t.Errorf("expected the labeled values A=a1 B=b1, but got %v", params)
+	}
+
+	_, failed, err = LabelParameters(context.Background(), ps, "aws-reserved")
+	if err != nil {
+		t.Fatalf("unexpected error labeling: %v", err)
+	}
+	if len(failed) != 2 {
+		t.Errorf("expected every key to fail with an invalid label, but got %v", failed)
+	}
+}
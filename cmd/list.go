@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/spf13/cobra"
+)
+
+var tagFlags []string
+
+// PrintParameterList prints the keys of params with their full names and
+// types. Values are never printed.
+func PrintParameterList(ps paramstore.Backend, params map[string]string) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	types := ps.ParameterTypes()
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Key", "Name", "Type"})
+	for _, k := range keys {
+		table.Append([]string{k, ps.FormatParamName(k), types[k]})
+	}
+	table.Render()
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the parameters of an environment",
+	Long:  "Lists the keys, names and types of the parameters of an environment in the AWS Parameter Store, without their values.",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		tags, err := parseTags(tagFlags)
+		if err != nil {
+			fmt.Printf("Error parsing tags: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{Tags: tags})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		params, err := ps.GetParameters(ctx)
+		if err != nil {
+			fmt.Printf("Error fetching parameters: %s \n", err)
+			os.Exit(1)
+		}
+
+		PrintParameterList(ps, params)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVar(&projFlag, "project", "", "The project to list")
+	listCmd.Flags().StringVar(&envFlag, "env", "", "The environment to list")
+	listCmd.Flags().StringArrayVar(&tagFlags, "tag", nil, "Only list parameters with this tag, as key=value. Can be repeated")
+
+	// Mark the required flags
+	listCmd.MarkFlagRequired("project")
+	listCmd.MarkFlagRequired("env")
+}
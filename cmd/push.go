@@ -4,12 +4,17 @@ Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/pytoolbelt/ime/pkg/config"
 	"github.com/pytoolbelt/ime/pkg/environment"
 	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/plan"
+	"github.com/pytoolbelt/ime/pkg/reference"
 	"github.com/spf13/cobra"
 )
 
@@ -17,25 +22,311 @@ var modeFlag string
 var envFlag string
 var projFlag string
 var overwriteFlag bool
+var listedFlag bool
+var dryRunFlag bool
+var outFlag string
 
-func AddParameters(ps *paramstore.ParamStore, ef *environment.EnvFile) error {
-	fmt.Print("adding parameters to parameter store")
-	return nil
+// PushSummary records what happened to each key during a push.
+type PushSummary struct {
+	Created []string
+	Updated []string
+	Deleted []string
+	Pulled  []string
+	Skipped []string
+	Failed  map[string]error
+}
+
+func NewPushSummary() *PushSummary {
+	return &PushSummary{Failed: make(map[string]error)}
 }
 
-func DeleteParameters(ps *paramstore.ParamStore, ef *environment.EnvFile) error {
-	fmt.Print("deleting parameters from parameter store")
+func (s *PushSummary) Print() {
+	fmt.Printf("Created: %d Updated: %d Deleted: %d Pulled: %d Skipped: %d Failed: %d \n",
+		len(s.Created), len(s.Updated), len(s.Deleted), len(s.Pulled), len(s.Skipped), len(s.Failed))
+
+	keys := make([]string, 0, len(s.Failed))
+	for k := range s.Failed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Printf("  failed %s: %s \n", k, s.Failed[k])
+	}
+}
+
+func (s *PushSummary) Err() error {
+	if len(s.Failed) > 0 {
+		return fmt.Errorf("%d parameter(s) failed", len(s.Failed))
+	}
 	return nil
 }
 
-func MergeParameters(ps *paramstore.ParamStore, ef *environment.EnvFile) error {
-	fmt.Print("merging parameters with parameter store")
+// BuildPlan computes the changeset a push in the given mode would make,
+// without writing anything.
+func BuildPlan(ctx context.Context, ps paramstore.Backend, refs *reference.Resolver, ef *environment.EnvFile, mode string, types config.ParameterTypes) (*plan.Plan, error) {
+	remote, err := ps.GetParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	normalizeStringLists(ps, ef, types)
+	normalizeReferences(ctx, refs, ef, remote)
+
+	p := &plan.Plan{
+		Project:           projFlag,
+		Env:               envFlag,
+		Path:              ps.Path(),
+		Mode:              mode,
+		RemoteFingerprint: plan.Fingerprint(remote),
+		LocalFingerprint:  plan.Fingerprint(ef.Vars),
+	}
+
+	switch mode {
+	case plan.ModeAdd:
+		p.Changes = plan.Add(ef.Vars, remote, overwriteFlag)
+
+	case plan.ModeDelete:
+		p.Changes = plan.Delete(ef.Vars, remote, listedFlag)
+
+	case plan.ModeMerge:
+		base := environment.NewBaselineForEnvFile(ef)
+		if err := base.Load(); err != nil {
+			return nil, err
+		}
+		p.Changes = plan.Merge(ef.Vars, remote, base)
+
+	default:
+		return nil, fmt.Errorf("invalid mode: %s", mode)
+	}
+
+	p.SetTypes(types.TypeOf)
+	return p, nil
+}
+
+// CheckPlan refuses a saved plan once the parameter store, or for a merge the
+// env file, no longer looks the way it did when the plan was made.
+func CheckPlan(ctx context.Context, ps paramstore.Backend, refs *reference.Resolver, ef *environment.EnvFile, p *plan.Plan, types config.ParameterTypes) error {
+	if p.Path != ps.Path() {
+		return fmt.Errorf("plan was made for %s, not %s", p.Path, ps.Path())
+	}
+
+	remote, err := ps.GetParameters(ctx)
+	if err != nil {
+		return err
+	}
+	normalizeStringLists(ps, ef, types)
+	normalizeReferences(ctx, refs, ef, remote)
+
+	if plan.Fingerprint(remote) != p.RemoteFingerprint {
+		return fmt.Errorf("parameters under %s changed after the plan was made, make a new plan", p.Path)
+	}
+
+	if p.Mode == plan.ModeMerge && plan.Fingerprint(ef.Vars) != p.LocalFingerprint {
+		return fmt.Errorf("env file %s changed after the plan was made, make a new plan", ef.Path)
+	}
 	return nil
 }
 
+// putParameters writes params, prints every key written and records every
+// key that failed in summary. It returns the keys written, in params order.
+func putParameters(ctx context.Context, ps paramstore.Backend, params []paramstore.Parameter, overwrite bool, verb string, summary *PushSummary) []string {
+	if len(params) == 0 {
+		return nil
+	}
+
+	versions, err := ps.PutParameters(ctx, params, overwrite)
+	var putErr *paramstore.PutError
+	switch {
+	case errors.As(err, &putErr):
+		for k, keyErr := range putErr.Failed {
+			summary.Failed[k] = keyErr
+		}
+	case err != nil:
+		for _, param := range params {
+			summary.Failed[param.Key] = err
+		}
+	}
+	return printWritten(ps, params, versions, verb)
+}
+
+// printWritten prints every param with a version in versions and returns
+// their keys, in params order.
+func printWritten(ps paramstore.Backend, params []paramstore.Parameter, versions map[string]int64, verb string) []string {
+	var written []string
+	for _, param := range params {
+		version, ok := versions[param.Key]
+		if !ok {
+			continue
+		}
+		fmt.Printf("Parameter %s: %s Version: %d \n", verb, ps.FormatParamName(param.Key), version)
+		written = append(written, param.Key)
+	}
+	return written
+}
+
+// deleteParameters deletes keys, prints every key deleted and records every
+// key that failed in summary. It returns the keys deleted.
+func deleteParameters(ctx context.Context, ps paramstore.Backend, keys []string, summary *PushSummary) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	deleted, failed := ps.DeleteParameters(ctx, keys)
+	for k, err := range failed {
+		summary.Failed[k] = err
+	}
+	return printDeleted(ps, deleted)
+}
+
+func printDeleted(ps paramstore.Backend, deleted []string) []string {
+	for _, key := range deleted {
+		fmt.Printf("Parameter deleted: %s \n", ps.FormatParamName(key))
+	}
+	return deleted
+}
+
+// writeChanges writes creates, updates and deletes, together when the
+// backend supports it, and records the outcome in summary.
+func writeChanges(ctx context.Context, ps paramstore.Backend, changes paramstore.Changes, summary *PushSummary) {
+	cw, ok := ps.(paramstore.ChangeWriter)
+	if !ok {
+		summary.Created = putParameters(ctx, ps, changes.Creates, false, "created", summary)
+		summary.Updated = putParameters(ctx, ps, changes.Updates, true, "updated", summary)
+		summary.Deleted = deleteParameters(ctx, ps, changes.Deletes, summary)
+		return
+	}
+
+	if len(changes.Creates) == 0 && len(changes.Updates) == 0 && len(changes.Deletes) == 0 {
+		return
+	}
+	versions, deleted, failed := cw.WriteChanges(ctx, changes)
+	for k, err := range failed {
+		summary.Failed[k] = err
+	}
+	summary.Created = printWritten(ps, changes.Creates, versions, "created")
+	summary.Updated = printWritten(ps, changes.Updates, versions, "updated")
+	summary.Deleted = printDeleted(ps, deleted)
+}
+
+// ValidatePlan checks every value the plan would write against the backend's
+// limits, so nothing is written when any of them would be refused.
+func ValidatePlan(ps paramstore.Backend, p *plan.Plan) error {
+	var params []paramstore.Parameter
+	for _, c := range p.Changes {
+		if c.Action == plan.ActionCreate || c.Action == plan.ActionUpdate {
+			params = append(params, paramstore.Parameter{Key: c.Key, Value: c.Value, Type: c.Type})
+		}
+	}
+	return ps.Validate(params)
+}
+
+// ApplyPlan writes the plan's changes. Conflicts are never applied; they are
+// reported and make ApplyPlan fail once everything else has been written. After
+// a merge the baseline is updated for every key both sides now agree on.
+// Pulled values are written the way fetch writes them: references resolved
+// and StringLists in listFormat. Nothing is written when ValidatePlan fails.
+func ApplyPlan(ctx context.Context, ps paramstore.Backend, refs *reference.Resolver, ef *environment.EnvFile, p *plan.Plan, listFormat string) error {
+	if err := ValidatePlan(ps, p); err != nil {
+		return err
+	}
+
+	summary := NewPushSummary()
+	var changes paramstore.Changes
+	var conflicts []plan.Change
+	localChanged := false
+
+	for _, c := range p.Changes {
+		switch c.Action {
+		case plan.ActionCreate:
+			changes.Creates = append(changes.Creates, paramstore.Parameter{Key: c.Key, Value: c.Value, Type: c.Type})
+
+		case plan.ActionUpdate:
+			changes.Updates = append(changes.Updates, paramstore.Parameter{Key: c.Key, Value: c.Value, Type: c.Type})
+
+		case plan.ActionDelete:
+			changes.Deletes = append(changes.Deletes, c.Key)
+
+		case plan.ActionPull:
+			if c.Remove {
+				ef.Delete(c.Key)
+				fmt.Printf("Removed locally: %s \n", c.Key)
+			} else {
+				value, err := refs.Resolve(ctx, c.Value)
+				if err != nil {
+					summary.Failed[c.Key] = err
+					continue
+				}
+				formatted := paramstore.FormatStringLists(ps, map[string]string{c.Key: value}, listFormat)
+				ef.Set(c.Key, formatted[c.Key])
+				fmt.Printf("Pulled: %s \n", c.Key)
+			}
+			localChanged = true
+			summary.Pulled = append(summary.Pulled, c.Key)
+
+		case plan.ActionConflict:
+			conflicts = append(conflicts, c)
+
+		default:
+			summary.Skipped = append(summary.Skipped, c.Key)
+		}
+	}
+
+	writeChanges(ctx, ps, changes, summary)
+
+	if p.Mode == plan.ModeMerge {
+		if err := saveMergeResult(ef, p, summary, localChanged); err != nil {
+			return err
+		}
+	}
+
+	summary.Print()
+
+	if len(conflicts) > 0 {
+		for _, c := range conflicts {
+			fmt.Printf("  conflict %s: %s \n", c.Key, c.Reason)
+		}
+		return fmt.Errorf("%d conflicting key(s) were not merged, resolve them and merge again", len(conflicts))
+	}
+	return summary.Err()
+}
+
+func saveMergeResult(ef *environment.EnvFile, p *plan.Plan, summary *PushSummary, localChanged bool) error {
+	if localChanged {
+		if err := ef.WriteEnvFile(); err != nil {
+			return err
+		}
+	}
+
+	base := environment.NewBaselineForEnvFile(ef)
+	if err := base.Load(); err != nil {
+		return err
+	}
+
+	for _, c := range p.Changes {
+		if c.Action == plan.ActionConflict {
+			continue
+		}
+		if _, failed := summary.Failed[c.Key]; failed {
+			continue
+		}
+
+		// Both sides now agree on the key, so it becomes the new baseline.
+		// Like fetch, a pull records the stored value rather than the
+		// resolved one written to the env file.
+		if c.Action == plan.ActionPull && !c.Remove {
+			base.Set(c.Key, c.Value)
+		} else if value, ok := ef.Vars[c.Key]; ok {
+			base.Set(c.Key, value)
+		} else {
+			base.Delete(c.Key)
+		}
+	}
+	return base.Save()
+}
+
 func IsValidMode(mode string) bool {
 	switch mode {
-	case "add", "delete", "merge":
+	case plan.ModeAdd, plan.ModeDelete, plan.ModeMerge:
 		return true
 	default:
 		return false
@@ -72,49 +363,64 @@ var pushCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		psPath, err := cfg.FormatParameterStorePath(projFlag, envFlag)
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
 		if err != nil {
-			fmt.Printf("Error formatting parameter store path: %s \n", err)
+			fmt.Printf("Error getting timeouts: %s \n", err)
 			os.Exit(1)
 		}
+		defer cancel()
 
-		ps, err := paramstore.NewParamStore(psPath)
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{})
 		if err != nil {
-			fmt.Printf("Error creating ParamStore: %s \n", err)
+			fmt.Printf("Error creating backend: %s \n", err)
 			os.Exit(1)
 		}
 
-		switch modeFlag {
+		types, err := parameterTypes(cfg, projFlag, envFlag, ef)
+		if err != nil {
+			fmt.Printf("Error getting parameter types: %s \n", err)
+			os.Exit(1)
+		}
 
-		case "add":
-			fmt.Printf("Adding parameters to %s \n", psPath)
-			if err := AddParameters(ps, ef); err != nil {
-				fmt.Printf("Error adding parameters: %s \n", err)
-				os.Exit(1)
-			}
-		case "delete":
-			fmt.Printf("Deleting parameters from %s \n", psPath)
-			if err := DeleteParameters(ps, ef); err != nil {
-				fmt.Printf("Error deleting parameters: %s \n", err)
+		listFormat, err := cfg.GetStringListFormat(projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting string list format: %s \n", err)
+			os.Exit(1)
+		}
+
+		refs := newResolver(cfg, projFlag, envFlag, ps)
+		p, err := BuildPlan(ctx, ps, refs, ef, modeFlag, types)
+		if err != nil {
+			fmt.Printf("Error planning push: %s \n", err)
+			os.Exit(1)
+		}
+
+		if dryRunFlag || outFlag != "" {
+			p.PrintTable()
+
+			if err := ValidatePlan(ps, p); err != nil {
+				fmt.Printf("Error validating plan: %s \n", err)
 				os.Exit(1)
 			}
-		case "merge":
-			fmt.Printf("Merging parameters with %s \n", psPath)
-			if err := MergeParameters(ps, ef); err != nil {
-				fmt.Printf("Error merging parameters: %s \n", err)
+		}
+
+		if outFlag != "" {
+			if err := p.Save(outFlag); err != nil {
+				fmt.Printf("Error saving plan: %s \n", err)
 				os.Exit(1)
 			}
-		default:
-			fmt.Printf("Invalid mode: %s \n", modeFlag)
-			os.Exit(1)
+			fmt.Printf("Plan saved to %s, it contains parameter values so keep it safe. Run it with: ime apply %s \n", outFlag, outFlag)
 		}
 
-		// eParams, err := ps.GetParameters()
-		// if err != nil {
-		// 	fmt.Printf("Error getting parameters: %s \n", err)
-		// 	os.Exit(1)
-		// }
+		if dryRunFlag || outFlag != "" {
+			os.Exit(0)
+		}
 
+		fmt.Printf("Pushing parameters to %s (%s) \n", ps.Path(), modeFlag)
+		if err := ApplyPlan(ctx, ps, refs, ef, p, listFormat); err != nil {
+			fmt.Printf("Error pushing parameters: %s \n", err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -125,6 +431,9 @@ func init() {
 	pushCmd.Flags().StringVar(&modeFlag, "mode", "add", "Mode of operation: add, delete, or merge")
 
 	pushCmd.Flags().BoolVar(&overwriteFlag, "overwrite", false, "Overwrite existing parameters in Parameter Store")
+	pushCmd.Flags().BoolVar(&listedFlag, "listed", false, "In delete mode, delete the keys listed in the env file instead of the keys missing from it")
+	pushCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the changes the push would make without making them")
+	pushCmd.Flags().StringVar(&outFlag, "out", "", "Save the plan to a file for 'ime apply' instead of pushing")
 
 	// Mark the required flags
 	pushCmd.MarkFlagRequired("project")
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/paramstore/fakesecrets"
+	"github.com/pytoolbelt/ime/pkg/paramstore/fakessm"
+	"github.com/pytoolbelt/ime/pkg/plan"
+	"github.com/pytoolbelt/ime/pkg/reference"
+)
+
+const testPath = "/global/project1/dev"
+
+func newTestBackend(t *testing.T, remote map[string]string) (*paramstore.ParamStore, *fakessm.Client) {
+	t.Helper()
+	client := fakessm.New()
+
+	seed := make(map[string]string)
+	for k, v := range remote {
+		seed[testPath+"/"+k] = v
+	}
+	client.Seed(seed)
+
+	return paramstore.NewParamStoreWithClient(client, testPath), client
+}
+
+// newTestResolver resolves references into the test backend only.
+func newTestResolver(ps paramstore.Backend) *reference.Resolver {
+	return newResolver(nil, "project1", "dev", ps)
+}
+
+func newTestEnvFile(t *testing.T, content string) *environment.EnvFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	ef := environment.NewEnvFileFromPath(path)
+	if err := ef.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+	return ef
+}
+
+func resetPushFlags(t *testing.T) {
+	t.Cleanup(func() {
+		overwriteFlag = false
+		listedFlag = false
+	})
+}
+
+func push(t *testing.T, ps paramstore.Backend, ef *environment.EnvFile, mode string) error {
+	t.Helper()
+	return pushWithListFormat(t, ps, ef, mode, "csv")
+}
+
+func pushWithListFormat(t *testing.T, ps paramstore.Backend, ef *environment.EnvFile, mode, listFormat string) error {
+	t.Helper()
+	refs := newTestResolver(ps)
+	p, err := BuildPlan(context.Background(), ps, refs, ef, mode, config.ParameterTypes{})
+	if err != nil {
+		t.Fatalf("unexpected error planning %s: %v", mode, err)
+	}
+	return ApplyPlan(context.Background(), ps, refs, ef, p, listFormat)
+}
+
+func assertRemote(t *testing.T, ps paramstore.Backend, expected map[string]string) {
+	t.Helper()
+	remote, err := ps.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+
+	if len(remote) != len(expected) {
+		t.Errorf("expected %d parameters, but got %v", len(expected), remote)
+	}
+	for k, v := range expected {
+		if remote[k] != v {
+			t.Errorf("expected %s=%q, but got %q", k, v, remote[k])
+		}
+	}
+}
+
+func TestPushAdd(t *testing.T) {
+	resetPushFlags(t)
+	ps, _ := newTestBackend(t, map[string]string{"EXISTING": "remote", "REMOTE_ONLY": "x"})
+	ef := newTestEnvFile(t, "NEW=local\nEXISTING=local\n")
+
+	if err := push(t, ps, ef, plan.ModeAdd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{"NEW": "local", "EXISTING": "remote", "REMOTE_ONLY": "x"})
+
+	overwriteFlag = true
+	if err := push(t, ps, ef, plan.ModeAdd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{"NEW": "local", "EXISTING": "local", "REMOTE_ONLY": "x"})
+}
+
+func TestPushDelete(t *testing.T) {
+	resetPushFlags(t)
+	remote := map[string]string{"KEEP": "1"}
+	for _, k := range []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L"} {
+		remote["RETIRED_"+k] = "old"
+	}
+	ps, client := newTestBackend(t, remote)
+	ef := newTestEnvFile(t, "KEEP=1\n")
+
+	if err := push(t, ps, ef, plan.ModeDelete); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{"KEEP": "1"})
+
+	if calls := client.Calls["DeleteParameters"]; calls != 2 {
+		t.Errorf("expected 2 delete batches, but got %d", calls)
+	}
+
+	listedFlag = true
+	if err := push(t, ps, ef, plan.ModeDelete); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{})
+}
+
+func TestPushMerge(t *testing.T) {
+	resetPushFlags(t)
+	ps, client := newTestBackend(t, map[string]string{"SHARED": "1", "MINE": "1", "THEIRS": "1", "BOTH": "1"})
+	ef := newTestEnvFile(t, "# comment\nSHARED=1\nMINE=1\nTHEIRS=1\nBOTH=1\n")
+
+	// The first merge has nothing to do and records the baseline.
+	if err := push(t, ps, ef, plan.ModeMerge); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ef.Set("MINE", "2")
+	ef.Set("BOTH", "local")
+	client.Seed(map[string]string{testPath + "/THEIRS": "2", testPath + "/BOTH": "remote"})
+
+	if err := push(t, ps, ef, plan.ModeMerge); err == nil {
+		t.Errorf("expected the BOTH conflict to fail the merge, but got none")
+	}
+
+	assertRemote(t, ps, map[string]string{"SHARED": "1", "MINE": "2", "THEIRS": "2", "BOTH": "remote"})
+
+	loaded := environment.NewEnvFileFromPath(ef.Path)
+	if err := loaded.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+	if loaded.Vars["THEIRS"] != "2" || loaded.Vars["BOTH"] != "local" {
+		t.Errorf("expected THEIRS pulled and BOTH untouched, but got %v", loaded.Vars)
+	}
+}
+
+func TestPushMergePullsReferences(t *testing.T) {
+	resetPushFlags(t)
+	ps, client := newTestBackend(t, map[string]string{"DB_USER": "app", "DATABASE_URL": "postgres://localhost"})
+	ef := newTestEnvFile(t, "DB_USER=app\nDATABASE_URL=postgres://localhost\n")
+
+	if err := push(t, ps, ef, plan.ModeMerge); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ref := "postgres://{{ime:project1/dev/DB_USER}}@db"
+	client.Seed(map[string]string{testPath + "/DATABASE_URL": ref})
+
+	if err := push(t, ps, ef, plan.ModeMerge); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded := environment.NewEnvFileFromPath(ef.Path)
+	if err := loaded.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+	if loaded.Vars["DATABASE_URL"] != "postgres://app@db" {
+		t.Errorf("expected the pulled reference resolved, but got %q", loaded.Vars["DATABASE_URL"])
+	}
+
+	base := environment.NewBaselineForEnvFile(loaded)
+	if err := base.Load(); err != nil {
+		t.Fatalf("failed to load baseline: %v", err)
+	}
+	if !base.Matches("DATABASE_URL", ref, true) {
+		t.Errorf("expected the baseline to hold the stored reference")
+	}
+
+	// Merging the pulled file again changes nothing on either side.
+	if err := push(t, ps, loaded, plan.ModeMerge); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{"DB_USER": "app", "DATABASE_URL": ref})
+}
+
+func TestPushMergePullsStringListAsJSON(t *testing.T) {
+	resetPushFlags(t)
+	ps, _ := newTestBackend(t, nil)
+	if _, err := ps.PutParameter(context.Background(), paramstore.Parameter{Key: "HOSTS", Value: "a,b", Type: "StringList"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	ef := newTestEnvFile(t, "HOSTS=[\"a\",\"b\"]\n")
+
+	if err := pushWithListFormat(t, ps, ef, plan.ModeMerge, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := ps.PutParameter(context.Background(), paramstore.Parameter{Key: "HOSTS", Value: "a,b,c", Type: "StringList"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	if err := pushWithListFormat(t, ps, ef, plan.ModeMerge, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded := environment.NewEnvFileFromPath(ef.Path)
+	if err := loaded.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+	if loaded.Vars["HOSTS"] != `["a","b","c"]` {
+		t.Errorf("expected HOSTS pulled as a JSON array, but got %q", loaded.Vars["HOSTS"])
+	}
+
+	// Merging the pulled file again changes nothing on either side.
+	if err := pushWithListFormat(t, ps, loaded, plan.ModeMerge, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{"HOSTS": "a,b,c"})
+}
+
+func TestPushSecretsManager(t *testing.T) {
+	resetPushFlags(t)
+	client := fakesecrets.New()
+	client.Seed(testPath, `{"EXISTING":"remote","RETIRED":"old"}`)
+	ss := paramstore.NewSecretStoreWithClient(client, testPath)
+	ef := newTestEnvFile(t, "NEW=local\nEXISTING=local\n")
+
+	overwriteFlag = true
+	if err := push(t, ss, ef, plan.ModeAdd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ss, map[string]string{"NEW": "local", "EXISTING": "local", "RETIRED": "old"})
+
+	if err := push(t, ss, ef, plan.ModeDelete); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRemote(t, ss, map[string]string{"NEW": "local", "EXISTING": "local"})
+
+	// Each push wrote its creates, updates and deletes as one new version of
+	// the whole secret, after the seed.
+	if versions := client.Versions(testPath); len(versions) != 3 {
+		t.Errorf("expected 3 versions of the secret, but got %d", len(versions))
+	}
+
+	entries, err := DiffParameters(context.Background(), ss, newTestResolver(ss), ef, config.ParameterTypes{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no differences, but got %+v", entries)
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	resetPushFlags(t)
+	ps, client := newTestBackend(t, map[string]string{"A": "1"})
+	ef := newTestEnvFile(t, "A=1\nB=2\n")
+
+	p, err := BuildPlan(context.Background(), ps, newTestResolver(ps), ef, plan.ModeAdd, config.ParameterTypes{})
+	if err != nil {
+		t.Fatalf("unexpected error planning: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := p.Save(path); err != nil {
+		t.Fatalf("failed to save plan: %v", err)
+	}
+	saved, err := plan.Load(path)
+	if err != nil {
+		t.Fatalf("failed to load plan: %v", err)
+	}
+
+	if err := CheckPlan(context.Background(), ps, newTestResolver(ps), ef, saved, config.ParameterTypes{}); err != nil {
+		t.Errorf("unexpected error checking an up to date plan: %v", err)
+	}
+
+	client.Seed(map[string]string{testPath + "/A": "changed"})
+	if err := CheckPlan(context.Background(), ps, newTestResolver(ps), ef, saved, config.ParameterTypes{}); err == nil {
+		t.Errorf("expected a stale plan to be refused, but got none")
+	}
+}
+
+func TestPushTypes(t *testing.T) {
+	resetPushFlags(t)
+	ps, client := newTestBackend(t, nil)
+	ef := newTestEnvFile(t, "# ime:type=String\nURL=https://example.com\nHOSTS=a,b\nSECRET=x\n")
+
+	types, err := parameterTypes(&config.Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]config.Project{
+			"project1": {
+				Prefix:       "/project1",
+				Types:        []config.KeyType{{Key: "HOSTS", Type: "StringList"}},
+				Environments: map[string]config.Environment{"dev": {Prefix: "/dev"}},
+			},
+		},
+	}, "project1", "dev", ef)
+	if err != nil {
+		t.Fatalf("unexpected error resolving types: %v", err)
+	}
+
+	refs := newTestResolver(ps)
+	p, err := BuildPlan(context.Background(), ps, refs, ef, plan.ModeAdd, types)
+	if err != nil {
+		t.Fatalf("unexpected error planning: %v", err)
+	}
+	if err := ApplyPlan(context.Background(), ps, refs, ef, p, "csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"URL": "String", "HOSTS": "StringList", "SECRET": "SecureString"}
+	for key, paramType := range expected {
+		versions := client.Versions(testPath + "/" + key)
+		if len(versions) != 1 || string(versions[0].Type) != paramType {
+			t.Errorf("expected %s to be a %s, but got %+v", key, paramType, versions)
+		}
+	}
+}
+
+func TestPushValidatesBeforeWriting(t *testing.T) {
+	resetPushFlags(t)
+	ps, client := newTestBackend(t, nil)
+	big := strings.Repeat("x", 5000)
+	ef := newTestEnvFile(t, "A=ok\nBIG="+big+"\nEMPTY=\nZ=ok\n")
+
+	err := push(t, ps, ef, plan.ModeAdd)
+	var invalid *paramstore.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError, but got %v", err)
+	}
+	if len(invalid.Violations) != 2 {
+		t.Errorf("expected violations for BIG and EMPTY, but got %v", invalid.Violations)
+	}
+	if client.Calls["PutParameter"] != 0 {
+		t.Errorf("expected no writes, but got %d PutParameter calls", client.Calls["PutParameter"])
+	}
+
+	ps.Tier = paramstore.TierAdvanced
+	ef.Set("EMPTY", "set")
+	if err := push(t, ps, ef, plan.ModeAdd); err != nil {
+		t.Fatalf("unexpected error with the advanced tier: %v", err)
+	}
+	assertRemote(t, ps, map[string]string{"A": "ok", "BIG": big, "EMPTY": "set", "Z": "ok"})
+}
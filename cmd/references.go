@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/environment"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/reference"
+)
+
+// newResolver returns a resolver for the references in values read from ps,
+// the backend of the given environment. {{ime:project/env/KEY}} references
+// are read through that environment's backend, and {{ime:/path/KEY}}
+// references through one configured like ps. Without cfg, only references
+// into ps itself resolve.
+func newResolver(cfg *config.Config, projectName, environmentName string, ps paramstore.Backend) *reference.Resolver {
+	backends := map[string]paramstore.Backend{
+		projectName + "/" + environmentName: ps,
+		ps.Path():                           ps,
+	}
+
+	return reference.NewResolver(func(ctx context.Context, ref reference.Ref) (string, error) {
+		id, key := ref.Project+"/"+ref.Env, ref.Key
+		if ref.Name != "" {
+			id, key = path.Dir(ref.Name), path.Base(ref.Name)
+		}
+
+		b, ok := backends[id]
+		if !ok {
+			if cfg == nil {
+				return "", fmt.Errorf("no configuration to reach %s", ref)
+			}
+
+			var err error
+			if ref.Name != "" {
+				b, err = newPathBackend(ctx, cfg, projectName, environmentName, id)
+			} else {
+				b, err = newBackend(ctx, cfg, ref.Project, ref.Env, paramstore.Filter{})
+			}
+			if err != nil {
+				return "", err
+			}
+			backends[id] = b
+		}
+
+		params, missing, err := b.GetParametersByName(ctx, []string{key})
+		if err != nil {
+			return "", err
+		}
+		if len(missing) > 0 {
+			return "", reference.ErrNotFound
+		}
+		return params[key], nil
+	})
+}
+
+// newPathBackend returns a backend rooted at root, configured like the
+// environment's backend.
+func newPathBackend(ctx context.Context, cfg *config.Config, projectName, environmentName, root string) (paramstore.Backend, error) {
+	kind, opts, err := backendOptions(cfg, projectName, environmentName)
+	if err != nil {
+		return nil, err
+	}
+	opts.Path = root
+	opts.KeyMapping = paramstore.KeyMapping{}
+	return paramstore.NewBackend(ctx, kind, opts)
+}
+
+// normalizeReferences puts back the reference for every env file value that
+// equals what the parameter store's reference resolves to, so pushing a
+// fetched file keeps the reference instead of overwriting it with its value.
+func normalizeReferences(ctx context.Context, refs *reference.Resolver, ef *environment.EnvFile, remote map[string]string) {
+	for k, v := range ef.Vars {
+		raw, ok := remote[k]
+		if !ok || raw == v || !reference.Contains(raw) {
+			continue
+		}
+
+		// A reference that no longer resolves shows up as a change.
+		if resolved, err := refs.Resolve(ctx, raw); err == nil && resolved == v {
+			ef.Vars[k] = raw
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/spf13/cobra"
+)
+
+var toVersionFlag int64
+
+// RollbackParameter puts the value and type key had at version back as a new
+// version, and returns the number of that new version.
+func RollbackParameter(ctx context.Context, ps paramstore.Backend, key string, version int64) (int64, error) {
+	versions, err := ps.GetParameterHistory(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, v := range versions {
+		if v.Version != version {
+			continue
+		}
+		return ps.PutParameter(ctx, paramstore.Parameter{Key: key, Value: v.Value, Type: v.Type}, true)
+	}
+	return 0, fmt.Errorf("parameter %s has no version %d", key, version)
+}
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback KEY",
+	Short: "Restore an earlier version of a parameter",
+	Long: "Puts the value of an earlier version of a parameter back as its latest version. " +
+		"Use 'ime history' to find the version to restore.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		if err := findParameter(ctx, ps, key); err != nil {
+			fmt.Printf("Error finding parameter: %s \n", err)
+			os.Exit(1)
+		}
+
+		version, err := RollbackParameter(ctx, ps, key, toVersionFlag)
+		if err != nil {
+			fmt.Printf("Error rolling back parameter: %s \n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Rolled %s back to version %d, saved as version %d \n", ps.FormatParamName(key), toVersionFlag, version)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.Flags().StringVar(&projFlag, "project", "", "The project of the parameter")
+	rollbackCmd.Flags().StringVar(&envFlag, "env", "", "The environment of the parameter")
+	rollbackCmd.Flags().Int64Var(&toVersionFlag, "to-version", 0, "The version to restore")
+
+	// Mark the required flags
+	rollbackCmd.MarkFlagRequired("project")
+	rollbackCmd.MarkFlagRequired("env")
+	rollbackCmd.MarkFlagRequired("to-version")
+}
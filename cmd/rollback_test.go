@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+)
+
+func TestRollbackParameter(t *testing.T) {
+	ps, client := newTestBackend(t, nil)
+
+	for _, v := range []string{"good", "bad"} {
+		if _, err := ps.PutParameter(context.Background(), paramstore.Parameter{Key: "KEY", Value: v, Type: "String"}, true); err != nil {
+			t.Fatalf("unexpected error putting parameter: %v", err)
+		}
+	}
+
+	version, err := RollbackParameter(context.Background(), ps, "KEY", 1)
+	if err != nil {
+		t.Fatalf("unexpected error rolling back: %v", err)
+	}
+	if version != 3 {
+		t.Errorf("expected the rollback to be saved as version 3, but got %d", version)
+	}
+	assertRemote(t, ps, map[string]string{"KEY": "good"})
+
+	versions := client.Versions(testPath + "/KEY")
+	if versions[2].Type != "String" {
+		t.Errorf("expected the rolled back type String, but got %s", versions[2].Type)
+	}
+
+	if _, err := RollbackParameter(context.Background(), ps, "KEY", 9); err == nil {
+		t.Errorf("expected error for a missing version, but got none")
+	}
+}
+
+func TestFindParameter(t *testing.T) {
+	ps, client := newTestBackend(t, map[string]string{"db/host": "db.internal", "PORT": "8080"})
+	ps.KeyMapping = paramstore.KeyMapping{Separator: "_", Case: "upper"}
+
+	if err := findParameter(context.Background(), ps, "DB_HOST"); err != nil {
+		t.Fatalf("unexpected error finding a nested parameter: %v", err)
+	}
+	versions, err := ps.GetParameterHistory(context.Background(), "DB_HOST")
+	if err != nil || len(versions) != 1 || versions[0].Value != "db.internal" {
+		t.Errorf("expected the history of db/host, but got %v (%v)", versions, err)
+	}
+
+	if err := findParameter(context.Background(), ps, "MISSING"); err == nil {
+		t.Errorf("expected error for a missing parameter, but got none")
+	}
+	if calls := client.Calls["GetParametersByPath"]; calls != 0 {
+		t.Errorf("expected no reads of the whole path, but got %d", calls)
+	}
+}
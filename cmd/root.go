@@ -5,11 +5,14 @@ package cmd
 
 import (
 	"os"
+	"time"
 
 	"github.com/pytoolbelt/ime/pkg/config"
 	"github.com/spf13/cobra"
 )
 
+var timeoutFlag time.Duration
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "ime",
@@ -35,10 +38,11 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.ime.yaml)")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "Deadline for the whole command, e.g. 2m. Overrides timeout in ime.yaml")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	// init viper config
-	config.InitializeConfig()
+	// init viper config once a command runs, not on import
+	cobra.OnInitialize(config.InitializeConfig)
 }
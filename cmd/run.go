@@ -0,0 +1,105 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run -- command [args...]",
+	Short: "Run a command with parameters from the AWS Parameter Store",
+	Long: "Fetches an environment from the AWS Parameter Store and runs the command with the parameters " +
+		"set as environment variables. Nothing is written to disk, and the command's exit code is passed through.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		tags, err := parseTags(tagFlags)
+		if err != nil {
+			fmt.Printf("Error parsing tags: %s \n", err)
+			os.Exit(1)
+		}
+
+		keys, err := requestedKeys(cfg, projFlag, envFlag, keysFlag)
+		if err != nil {
+			fmt.Printf("Error getting keys: %s \n", err)
+			os.Exit(1)
+		}
+		if len(keys) > 0 && len(tags) > 0 {
+			fmt.Printf("Error: --tag can't be combined with a list of keys \n")
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{Tags: tags, Label: labelFlag})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		params, err := readParameters(ctx, ps, keys)
+		if err != nil {
+			fmt.Printf("Error fetching parameters: %s \n", err)
+			os.Exit(1)
+		}
+
+		params, err = newResolver(cfg, projFlag, envFlag, ps).ResolveAll(ctx, params)
+		if err != nil {
+			fmt.Printf("Error resolving references: %s \n", err)
+			os.Exit(1)
+		}
+
+		listFormat, err := cfg.GetStringListFormat(projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting string list format: %s \n", err)
+			os.Exit(1)
+		}
+		params = paramstore.FormatStringLists(ps, params, listFormat)
+
+		// The deadline covers fetching the parameters, not the command, and
+		// RunCommand forwards signals to the command itself.
+		cancel()
+
+		code, err := terminal.RunCommand(args, paramstore.FormatParamsAsEnv(params))
+		if err != nil {
+			fmt.Printf("Error running %s: %s \n", args[0], err)
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().StringVar(&projFlag, "project", "", "The project to run with")
+	runCmd.Flags().StringVar(&envFlag, "env", "", "The environment to run with")
+	runCmd.Flags().StringArrayVar(&tagFlags, "tag", nil, "Only use parameters with this tag, as key=value. Can be repeated")
+	runCmd.Flags().StringSliceVar(&keysFlag, "keys", nil, "Only use these keys, e.g. A,B,C. Overrides keys in ime.yaml")
+	runCmd.Flags().StringVar(&labelFlag, "label", "", "Use the versions carrying this label instead of the latest")
+
+	// Everything after the command name belongs to the command, not to ime.
+	runCmd.Flags().SetInterspersed(false)
+
+	// Mark the required flags
+	runCmd.MarkFlagRequired("project")
+	runCmd.MarkFlagRequired("env")
+}
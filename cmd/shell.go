@@ -0,0 +1,97 @@
+/*
+Copyright © 2024 Jesse Maitland jesse@pytoolbelt.com
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pytoolbelt/ime/pkg/config"
+	"github.com/pytoolbelt/ime/pkg/paramstore"
+	"github.com/pytoolbelt/ime/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+var promptFlag bool
+var forceFlag bool
+var keysFlag []string
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Start a subshell with parameters from the AWS Parameter Store",
+	Long: "Fetches an environment from the AWS Parameter Store and starts your $SHELL with the parameters " +
+		"set as environment variables. IME_PROJECT and IME_ENV are set to the session's project and environment.",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if current, ok := terminal.CurrentSession(); ok && !forceFlag {
+			fmt.Printf("Already in an ime session for project %s and environment %s, exit it first or pass --force \n", current.Project, current.Env)
+			os.Exit(1)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading configuration: %s \n", err)
+			os.Exit(1)
+		}
+
+		keys, err := requestedKeys(cfg, projFlag, envFlag, keysFlag)
+		if err != nil {
+			fmt.Printf("Error getting keys: %s \n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel, err := commandContext(cfg, projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting timeouts: %s \n", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
+		ps, err := newBackend(ctx, cfg, projFlag, envFlag, paramstore.Filter{})
+		if err != nil {
+			fmt.Printf("Error creating backend: %s \n", err)
+			os.Exit(1)
+		}
+
+		params, err := readParameters(ctx, ps, keys)
+		if err != nil {
+			fmt.Printf("Error fetching parameters: %s \n", err)
+			os.Exit(1)
+		}
+
+		params, err = newResolver(cfg, projFlag, envFlag, ps).ResolveAll(ctx, params)
+		if err != nil {
+			fmt.Printf("Error resolving references: %s \n", err)
+			os.Exit(1)
+		}
+
+		listFormat, err := cfg.GetStringListFormat(projFlag, envFlag)
+		if err != nil {
+			fmt.Printf("Error getting string list format: %s \n", err)
+			os.Exit(1)
+		}
+		params = paramstore.FormatStringLists(ps, params, listFormat)
+
+		session := terminal.Session{
+			Project: projFlag,
+			Env:     envFlag,
+			Prompt:  promptFlag,
+		}
+		terminal.StartSubshell(session, paramstore.FormatParamsAsEnv(params))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+	shellCmd.Flags().StringVar(&projFlag, "project", "", "The project to start a shell for")
+	shellCmd.Flags().StringVar(&envFlag, "env", "", "The environment to start a shell for")
+	shellCmd.Flags().BoolVar(&promptFlag, "prompt", false, "Prefix the shell prompt with the session's project and environment")
+	shellCmd.Flags().StringSliceVar(&keysFlag, "keys", nil, "Only set these keys, e.g. A,B,C. Overrides keys in ime.yaml")
+	shellCmd.Flags().BoolVar(&forceFlag, "force", false, "Start the shell even when already inside an ime session")
+
+	// Mark the required flags
+	shellCmd.MarkFlagRequired("project")
+	shellCmd.MarkFlagRequired("env")
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/viper"
@@ -17,13 +20,86 @@ type Config struct {
 }
 
 type Project struct {
-	Prefix       string                 `mapstructure:"prefix"`
-	Environments map[string]Environment `mapstructure:"environments"`
+	Prefix           string                 `mapstructure:"prefix"`
+	Backend          string                 `mapstructure:"backend"`
+	KeyMapping       KeyMapping             `mapstructure:"key_mapping"`
+	DefaultType      string                 `mapstructure:"default_type"`
+	Types            []KeyType              `mapstructure:"types"`
+	StringListFormat string                 `mapstructure:"string_list_format"`
+	KMSKeyID         string                 `mapstructure:"kms_key_id"`
+	Tags             []Tag                  `mapstructure:"tags"`
+	Tier             string                 `mapstructure:"tier"`
+	Timeout          string                 `mapstructure:"timeout"`
+	CallTimeout      string                 `mapstructure:"call_timeout"`
+	Keys             []string               `mapstructure:"keys"`
+	Region           string                 `mapstructure:"region"`
+	Profile          string                 `mapstructure:"profile"`
+	RoleARN          string                 `mapstructure:"role_arn"`
+	ExternalID       string                 `mapstructure:"external_id"`
+	SessionName      string                 `mapstructure:"session_name"`
+	EndpointURL      string                 `mapstructure:"endpoint_url"`
+	Policies         PolicySettings         `mapstructure:"policies"`
+	KeyPolicies      []KeyPolicySettings    `mapstructure:"key_policies"`
+	Environments     map[string]Environment `mapstructure:"environments"`
 }
 
 type Environment struct {
-	Prefix    string `mapstructure:"prefix"`
-	LocalPath string `mapstructure:"local_path"`
+	Prefix           string              `mapstructure:"prefix"`
+	LocalPath        string              `mapstructure:"local_path"`
+	Backend          string              `mapstructure:"backend"`
+	KeyMapping       KeyMapping          `mapstructure:"key_mapping"`
+	DefaultType      string              `mapstructure:"default_type"`
+	Types            []KeyType           `mapstructure:"types"`
+	StringListFormat string              `mapstructure:"string_list_format"`
+	KMSKeyID         string              `mapstructure:"kms_key_id"`
+	Tags             []Tag               `mapstructure:"tags"`
+	Tier             string              `mapstructure:"tier"`
+	Timeout          string              `mapstructure:"timeout"`
+	CallTimeout      string              `mapstructure:"call_timeout"`
+	Keys             []string            `mapstructure:"keys"`
+	Region           string              `mapstructure:"region"`
+	Profile          string              `mapstructure:"profile"`
+	RoleARN          string              `mapstructure:"role_arn"`
+	ExternalID       string              `mapstructure:"external_id"`
+	SessionName      string              `mapstructure:"session_name"`
+	EndpointURL      string              `mapstructure:"endpoint_url"`
+	Policies         PolicySettings      `mapstructure:"policies"`
+	KeyPolicies      []KeyPolicySettings `mapstructure:"key_policies"`
+}
+
+// KeyMapping controls how parameters nested below the environment path are
+// named as env vars. With separator "_" and case "upper", db/host becomes DB_HOST.
+type KeyMapping struct {
+	Separator string `mapstructure:"separator"`
+	Case      string `mapstructure:"case"`
+}
+
+// KeyType sets the parameter type of one key. Settings made per key, like this
+// one, KeyPolicySettings and Tag, are lists rather than maps because viper
+// lowercases map keys, which would turn FEATURE_FLAGS into feature_flags.
+type KeyType struct {
+	Key  string `mapstructure:"key"`
+	Type string `mapstructure:"type"`
+}
+
+// Tag is a tag put on every parameter of the environment.
+type Tag struct {
+	Key   string `mapstructure:"key"`
+	Value string `mapstructure:"value"`
+}
+
+// PolicySettings declare the SSM parameter policies put on parameters. Each
+// is a whole number of days or hours, such as 30d or 12h.
+type PolicySettings struct {
+	ExpiresAfter         string `mapstructure:"expires_after"`
+	NotifyBeforeExpiry   string `mapstructure:"notify_before_expiry"`
+	NotifyIfUnchangedFor string `mapstructure:"notify_if_unchanged_for"`
+}
+
+// KeyPolicySettings declare the policies of one key, over the defaults.
+type KeyPolicySettings struct {
+	Key            string `mapstructure:"key"`
+	PolicySettings `mapstructure:",squash"`
 }
 
 func (e *Environment) GetResolvedLocalPath() string {
@@ -54,6 +130,518 @@ func (c *Config) GetProject(projectName string) (*Project, error) {
 	return &project, nil
 }
 
+// GetBackend returns the secret backend for the environment. The environment's
+// setting wins over the project's, and an empty result means the default.
+func (c *Config) GetBackend(projectName, environmentName string) (string, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return "", err
+	}
+
+	if env.Backend != "" {
+		return env.Backend, nil
+	}
+	return c.Projects[projectName].Backend, nil
+}
+
+// GetKeyMapping returns the key mapping for the environment. Each setting the
+// environment leaves empty falls back to the project's.
+func (c *Config) GetKeyMapping(projectName, environmentName string) (KeyMapping, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return KeyMapping{}, err
+	}
+
+	mapping := c.Projects[projectName].KeyMapping
+	if env.KeyMapping.Separator != "" {
+		mapping.Separator = env.KeyMapping.Separator
+	}
+	if env.KeyMapping.Case != "" {
+		mapping.Case = env.KeyMapping.Case
+	}
+	return mapping, nil
+}
+
+// ParameterTypes gives the parameter type each key is stored as.
+type ParameterTypes struct {
+	Default string
+	Keys    map[string]string
+}
+
+// TypeOf returns the type for key, falling back to Default and then to
+// SecureString.
+func (t ParameterTypes) TypeOf(key string) string {
+	if keyType, ok := t.Keys[key]; ok {
+		return keyType
+	}
+	if t.Default != "" {
+		return t.Default
+	}
+	return "SecureString"
+}
+
+// GetParameterTypes merges the project's types with the environment's; the
+// environment wins for the default and for any key set in both.
+func (c *Config) GetParameterTypes(projectName, environmentName string) (ParameterTypes, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return ParameterTypes{}, err
+	}
+	project := c.Projects[projectName]
+
+	types := ParameterTypes{
+		Default: project.DefaultType,
+		Keys:    make(map[string]string),
+	}
+	if env.DefaultType != "" {
+		types.Default = env.DefaultType
+	}
+	for _, t := range project.Types {
+		types.Keys[t.Key] = t.Type
+	}
+	for _, t := range env.Types {
+		types.Keys[t.Key] = t.Type
+	}
+	return types, nil
+}
+
+// GetStringListFormat returns how StringList values are written to env vars:
+// csv (the default) or json.
+func (c *Config) GetStringListFormat(projectName, environmentName string) (string, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return "", err
+	}
+
+	format := c.Projects[projectName].StringListFormat
+	if env.StringListFormat != "" {
+		format = env.StringListFormat
+	}
+	if format == "" {
+		format = "csv"
+	}
+	return format, nil
+}
+
+// GetKMSKeyID returns the KMS key SecureString parameters are encrypted with.
+// The environment's key wins over the project's, and an empty result means
+// the account's default aws/ssm key.
+func (c *Config) GetKMSKeyID(projectName, environmentName string) (string, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return "", err
+	}
+
+	if env.KMSKeyID != "" {
+		return env.KMSKeyID, nil
+	}
+	return c.Projects[projectName].KMSKeyID, nil
+}
+
+// GetTags returns the tags put on every parameter of the environment: the
+// project's tags with the environment's merged over them.
+func (c *Config) GetTags(projectName, environmentName string) (map[string]string, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range c.Projects[projectName].Tags {
+		tags[tag.Key] = tag.Value
+	}
+	for _, tag := range env.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// GetTier returns the parameter tier for the environment: standard, advanced or
+// intelligent. The environment's setting wins over the project's, and the
+// default is standard.
+func (c *Config) GetTier(projectName, environmentName string) (string, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return "", err
+	}
+
+	tier := c.Projects[projectName].Tier
+	if env.Tier != "" {
+		tier = env.Tier
+	}
+	if tier == "" {
+		tier = "standard"
+	}
+	return tier, nil
+}
+
+// GetKeys returns the keys fetch, run and shell read for the environment. The
+// environment's list replaces the project's, and an empty result means every
+// parameter under the path.
+func (c *Config) GetKeys(projectName, environmentName string) ([]string, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(env.Keys) > 0 {
+		return env.Keys, nil
+	}
+	return c.Projects[projectName].Keys, nil
+}
+
+// AWS selects the account and region an environment's parameters live in.
+// Empty fields leave the AWS SDK's defaults alone, so ambient credentials,
+// AWS_PROFILE and AWS_REGION still apply.
+type AWS struct {
+	Region  string
+	Profile string
+	// RoleARN is assumed with the credentials from Profile before any call.
+	RoleARN     string
+	ExternalID  string
+	SessionName string
+	// EndpointURL replaces the regional endpoint, for example to reach
+	// LocalStack at http://localhost:4566.
+	EndpointURL string
+}
+
+// GetAWS returns the region, profile and role to reach the environment's
+// parameters with. Each setting the environment leaves empty falls back to
+// the project's.
+func (c *Config) GetAWS(projectName, environmentName string) (AWS, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return AWS{}, err
+	}
+	project := c.Projects[projectName]
+
+	aws := AWS{
+		Region:      project.Region,
+		Profile:     project.Profile,
+		RoleARN:     project.RoleARN,
+		ExternalID:  project.ExternalID,
+		SessionName: project.SessionName,
+		EndpointURL: project.EndpointURL,
+	}
+	settings := []struct {
+		env    string
+		target *string
+	}{
+		{env.Region, &aws.Region},
+		{env.Profile, &aws.Profile},
+		{env.RoleARN, &aws.RoleARN},
+		{env.ExternalID, &aws.ExternalID},
+		{env.SessionName, &aws.SessionName},
+		{env.EndpointURL, &aws.EndpointURL},
+	}
+	for _, s := range settings {
+		if s.env != "" {
+			*s.target = s.env
+		}
+	}
+
+	if aws.RoleARN == "" && (aws.ExternalID != "" || aws.SessionName != "") {
+		return AWS{}, fmt.Errorf("external_id and session_name need a role_arn to assume")
+	}
+	return aws, nil
+}
+
+// Policies are parsed policy settings. A zero field sets no policy.
+type Policies struct {
+	ExpiresAfter         time.Duration
+	NotifyBeforeExpiry   time.Duration
+	NotifyIfUnchangedFor time.Duration
+}
+
+// ParameterPolicies give the policies each key is written with. Keys holds
+// the keys with policies of their own, already merged over Default.
+type ParameterPolicies struct {
+	Default Policies
+	Keys    map[string]Policies
+}
+
+// GetPolicies merges the project's policies with the environment's. The
+// environment wins for every setting it makes, and a key's own settings win
+// over the environment's defaults.
+func (c *Config) GetPolicies(projectName, environmentName string) (ParameterPolicies, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return ParameterPolicies{}, err
+	}
+	project := c.Projects[projectName]
+
+	defaults := mergePolicySettings(project.Policies, env.Policies)
+	policies := ParameterPolicies{Keys: make(map[string]Policies)}
+	if policies.Default, err = parsePolicySettings(defaults); err != nil {
+		return ParameterPolicies{}, fmt.Errorf("policies: %w", err)
+	}
+
+	projectKeys, err := keyPolicySettings(project.KeyPolicies)
+	if err != nil {
+		return ParameterPolicies{}, err
+	}
+	envKeys, err := keyPolicySettings(env.KeyPolicies)
+	if err != nil {
+		return ParameterPolicies{}, err
+	}
+
+	keys := make(map[string]bool)
+	for k := range projectKeys {
+		keys[k] = true
+	}
+	for k := range envKeys {
+		keys[k] = true
+	}
+	for k := range keys {
+		settings := mergePolicySettings(defaults, mergePolicySettings(projectKeys[k], envKeys[k]))
+		if policies.Keys[k], err = parsePolicySettings(settings); err != nil {
+			return ParameterPolicies{}, fmt.Errorf("key_policies %s: %w", k, err)
+		}
+	}
+	return policies, nil
+}
+
+// keyPolicySettings indexes the key_policies entries by key.
+func keyPolicySettings(entries []KeyPolicySettings) (map[string]PolicySettings, error) {
+	settings := make(map[string]PolicySettings, len(entries))
+	for _, entry := range entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("key_policies: every entry needs a key")
+		}
+		if _, ok := settings[entry.Key]; ok {
+			return nil, fmt.Errorf("key_policies %s: key is set more than once", entry.Key)
+		}
+		settings[entry.Key] = entry.PolicySettings
+	}
+	return settings, nil
+}
+
+// mergePolicySettings returns base with every setting over makes replaced.
+func mergePolicySettings(base, over PolicySettings) PolicySettings {
+	if over.ExpiresAfter != "" {
+		base.ExpiresAfter = over.ExpiresAfter
+	}
+	if over.NotifyBeforeExpiry != "" {
+		base.NotifyBeforeExpiry = over.NotifyBeforeExpiry
+	}
+	if over.NotifyIfUnchangedFor != "" {
+		base.NotifyIfUnchangedFor = over.NotifyIfUnchangedFor
+	}
+	return base
+}
+
+func parsePolicySettings(settings PolicySettings) (Policies, error) {
+	var policies Policies
+	fields := []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"expires_after", settings.ExpiresAfter, &policies.ExpiresAfter},
+		{"notify_before_expiry", settings.NotifyBeforeExpiry, &policies.NotifyBeforeExpiry},
+		{"notify_if_unchanged_for", settings.NotifyIfUnchangedFor, &policies.NotifyIfUnchangedFor},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		d, err := parsePolicyDuration(f.value)
+		if err != nil {
+			return Policies{}, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.target = d
+	}
+
+	if policies.NotifyBeforeExpiry > 0 && policies.ExpiresAfter == 0 {
+		return Policies{}, fmt.Errorf("notify_before_expiry needs expires_after")
+	}
+	if policies.ExpiresAfter > 0 && policies.NotifyBeforeExpiry >= policies.ExpiresAfter {
+		return Policies{}, fmt.Errorf("notify_before_expiry must be shorter than expires_after")
+	}
+	return policies, nil
+}
+
+// parsePolicyDuration parses a whole number of days or hours, such as 30d or
+// 12h, the units SSM parameter policies are written in.
+func parsePolicyDuration(value string) (time.Duration, error) {
+	unit := time.Hour
+	number, ok := strings.CutSuffix(value, "h")
+	if !ok {
+		number, ok = strings.CutSuffix(value, "d")
+		unit = 24 * time.Hour
+	}
+	n, err := strconv.Atoi(number)
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a whole number of days or hours such as 30d or 12h, got %s", value)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Timeouts bound the calls ime makes for an environment. Total is the deadline
+// for a whole command and Call the deadline for each single call. Zero means
+// no limit for Total and the backend's default for Call.
+type Timeouts struct {
+	Total time.Duration
+	Call  time.Duration
+}
+
+// GetTimeouts returns the timeout and call_timeout settings for the
+// environment. The environment's settings win over the project's.
+func (c *Config) GetTimeouts(projectName, environmentName string) (Timeouts, error) {
+	env, err := c.GetEnvironment(projectName, environmentName)
+	if err != nil {
+		return Timeouts{}, err
+	}
+	project := c.Projects[projectName]
+
+	var timeouts Timeouts
+	settings := []struct {
+		name         string
+		project, env string
+		target       *time.Duration
+	}{
+		{"timeout", project.Timeout, env.Timeout, &timeouts.Total},
+		{"call_timeout", project.CallTimeout, env.CallTimeout, &timeouts.Call},
+	}
+	for _, s := range settings {
+		value := s.project
+		if s.env != "" {
+			value = s.env
+		}
+		if value == "" {
+			continue
+		}
+		d, err := parseTimeout(value)
+		if err != nil {
+			return Timeouts{}, fmt.Errorf("%s: %w", s.name, err)
+		}
+		*s.target = d
+	}
+	return timeouts, nil
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration such as 30s or 2m, got %s", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative, got %s", value)
+	}
+	return d, nil
+}
+
+func validateTimeouts(timeout, callTimeout string) error {
+	if timeout != "" {
+		if _, err := parseTimeout(timeout); err != nil {
+			return fmt.Errorf("timeout: %w", err)
+		}
+	}
+	if callTimeout != "" {
+		if _, err := parseTimeout(callTimeout); err != nil {
+			return fmt.Errorf("call_timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateRoleARN(roleARN string) error {
+	if roleARN != "" && !strings.HasPrefix(roleARN, "arn:") {
+		return fmt.Errorf("role_arn must be an ARN such as arn:aws:iam::123456789012:role/ime, got %s", roleARN)
+	}
+	return nil
+}
+
+func validateEndpointURL(endpointURL string) error {
+	if endpointURL == "" {
+		return nil
+	}
+	u, err := url.Parse(endpointURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint_url must be an http or https URL such as http://localhost:4566, got %s", endpointURL)
+	}
+	return nil
+}
+
+func ValidateParameterType(paramType string) error {
+	switch paramType {
+	case "String", "StringList", "SecureString":
+		return nil
+	default:
+		return fmt.Errorf("parameter type must be one of String, StringList or SecureString, got %s", paramType)
+	}
+}
+
+func validateTypes(defaultType string, types []KeyType, stringListFormat string) error {
+	if defaultType != "" {
+		if err := ValidateParameterType(defaultType); err != nil {
+			return fmt.Errorf("default_type: %w", err)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, t := range types {
+		if t.Key == "" {
+			return fmt.Errorf("types: every entry needs a key")
+		}
+		if seen[t.Key] {
+			return fmt.Errorf("types %s: key is set more than once", t.Key)
+		}
+		seen[t.Key] = true
+		if err := ValidateParameterType(t.Type); err != nil {
+			return fmt.Errorf("types %s: %w", t.Key, err)
+		}
+	}
+	switch stringListFormat {
+	case "", "csv", "json":
+		return nil
+	default:
+		return fmt.Errorf("string_list_format must be csv or json, got %s", stringListFormat)
+	}
+}
+
+func validateTags(tags []Tag) error {
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		if tag.Key == "" {
+			return fmt.Errorf("tags: every entry needs a key")
+		}
+		if seen[tag.Key] {
+			return fmt.Errorf("tags %s: key is set more than once", tag.Key)
+		}
+		seen[tag.Key] = true
+	}
+	return nil
+}
+
+func validateTier(tier string) error {
+	switch tier {
+	case "", "standard", "advanced", "intelligent":
+		return nil
+	default:
+		return fmt.Errorf("tier must be one of standard, advanced or intelligent, got %s", tier)
+	}
+}
+
+// validateKeySeparator checks the separator only holds characters env var
+// names can.
+func validateKeySeparator(separator string) error {
+	for _, r := range separator {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return fmt.Errorf("key_mapping separator may only hold letters, digits and underscores, got %s", separator)
+		}
+	}
+	return nil
+}
+
+func validateKeyCase(keyCase string) error {
+	switch keyCase {
+	case "", "preserve", "upper", "lower":
+		return nil
+	default:
+		return fmt.Errorf("key_mapping case must be one of preserve, upper or lower, got %s", keyCase)
+	}
+}
+
 // Function to validate the config
 func (c *Config) ValidateConfig() error {
 	if !strings.HasPrefix(c.GlobalPrefix, "/") {
@@ -65,10 +653,80 @@ func (c *Config) ValidateConfig() error {
 			return fmt.Errorf("prefix for project %s must start with '/'", projectName)
 		}
 
+		if err := validateKeyCase(project.KeyMapping.Case); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateKeySeparator(project.KeyMapping.Separator); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateTypes(project.DefaultType, project.Types, project.StringListFormat); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateTags(project.Tags); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateTier(project.Tier); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateTimeouts(project.Timeout, project.CallTimeout); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateRoleARN(project.RoleARN); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
+		if err := validateEndpointURL(project.EndpointURL); err != nil {
+			return fmt.Errorf("project %s: %w", projectName, err)
+		}
+
 		for envName, env := range project.Environments {
 			if !strings.HasPrefix(env.Prefix, "/") {
 				return fmt.Errorf("prefix for environment %s in project %s must start with '/'", envName, projectName)
 			}
+
+			if err := validateKeyCase(env.KeyMapping.Case); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateKeySeparator(env.KeyMapping.Separator); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateTypes(env.DefaultType, env.Types, env.StringListFormat); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateTags(env.Tags); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateTier(env.Tier); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateTimeouts(env.Timeout, env.CallTimeout); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateRoleARN(env.RoleARN); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			if err := validateEndpointURL(env.EndpointURL); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
+
+			// Policies are checked merged, as a key's settings may rely on
+			// the environment's or the project's.
+			if _, err := c.GetPolicies(projectName, envName); err != nil {
+				return fmt.Errorf("environment %s in project %s: %w", envName, projectName, err)
+			}
 		}
 	}
 
@@ -94,11 +752,12 @@ func (c *Config) FormatParameterStorePath(projectName, environmentName string) (
 // Method to print the config as a table
 func (c *Config) PrintTable() {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Project", "Environment", "Prefix", "Local Path"})
+	table.SetHeader([]string{"Project", "Environment", "Prefix", "Local Path", "Backend"})
 
 	for projectName, project := range c.Projects {
 		for envName, env := range project.Environments {
-			table.Append([]string{projectName, envName, env.Prefix, env.LocalPath})
+			backend, _ := c.GetBackend(projectName, envName)
+			table.Append([]string{projectName, envName, env.Prefix, env.LocalPath, backend})
 		}
 	}
 
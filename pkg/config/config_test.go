@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -177,6 +180,84 @@ func TestValidateConfig(t *testing.T) {
 			},
 			true,
 		},
+		{
+			Config{
+				GlobalPrefix: "/global",
+				Projects: map[string]Project{
+					"project1": {
+						Prefix: "/project1",
+						Environments: map[string]Environment{
+							"dev": {
+								Prefix: "/dev",
+								Tier:   "premium",
+							},
+						},
+					},
+				},
+			},
+			true,
+		},
+		{
+			Config{
+				GlobalPrefix: "/global",
+				Projects: map[string]Project{
+					"project1": {
+						Prefix:  "/project1",
+						RoleARN: "billing-admin",
+						Environments: map[string]Environment{
+							"dev": {Prefix: "/dev"},
+						},
+					},
+				},
+			},
+			true,
+		},
+		{
+			Config{
+				GlobalPrefix: "/global",
+				Projects: map[string]Project{
+					"project1": {
+						Prefix: "/project1",
+						Environments: map[string]Environment{
+							"dev": {Prefix: "/dev", EndpointURL: "localhost:4566"},
+						},
+					},
+				},
+			},
+			true,
+		},
+		{
+			Config{
+				GlobalPrefix: "/global",
+				Projects: map[string]Project{
+					"project1": {
+						Prefix: "/project1",
+						Environments: map[string]Environment{
+							"dev": {
+								Prefix:   "/dev",
+								Policies: PolicySettings{ExpiresAfter: "1d", NotifyBeforeExpiry: "2d"},
+							},
+						},
+					},
+				},
+			},
+			true,
+		},
+		{
+			Config{
+				GlobalPrefix: "/global",
+				Projects: map[string]Project{
+					"project1": {
+						Prefix:     "/project1",
+						KeyMapping: KeyMapping{Separator: "-"},
+						Environments: map[string]Environment{
+							"dev": {Prefix: "/dev"},
+						},
+					},
+				},
+			},
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -238,6 +319,429 @@ func TestFormatParameterStorePath(t *testing.T) {
 	}
 }
 
+func TestGetBackend(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix:  "/project1",
+				Backend: "ssm",
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", Backend: "other"},
+				},
+			},
+			"project2": {
+				Prefix: "/project2",
+				Environments: map[string]Environment{
+					"dev": {Prefix: "/dev"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		projectName     string
+		environmentName string
+		expectedBackend string
+		expectError     bool
+	}{
+		{"project1", "dev", "ssm", false},
+		{"project1", "prod", "other", false},
+		{"project2", "dev", "", false},
+		{"project2", "prod", "", true},
+	}
+
+	for _, tt := range tests {
+		backend, err := config.GetBackend(tt.projectName, tt.environmentName)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("expected error for project %s and environment %s, but got none", tt.projectName, tt.environmentName)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("unexpected error for project %s and environment %s: %v", tt.projectName, tt.environmentName, err)
+			} else if backend != tt.expectedBackend {
+				t.Errorf("expected backend %s, but got %s", tt.expectedBackend, backend)
+			}
+		}
+	}
+}
+
+func TestGetKeyMapping(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix:     "/project1",
+				KeyMapping: KeyMapping{Separator: "__", Case: "upper"},
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", KeyMapping: KeyMapping{Case: "lower"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName string
+		expected        KeyMapping
+	}{
+		{"dev", KeyMapping{Separator: "__", Case: "upper"}},
+		{"prod", KeyMapping{Separator: "__", Case: "lower"}},
+	}
+
+	for _, tt := range tests {
+		mapping, err := config.GetKeyMapping("project1", tt.environmentName)
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if mapping != tt.expected {
+			t.Errorf("expected key mapping %+v, but got %+v", tt.expected, mapping)
+		}
+	}
+}
+
+func TestGetParameterTypes(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix:      "/project1",
+				DefaultType: "String",
+				Types:       []KeyType{{Key: "API_KEY", Type: "SecureString"}, {Key: "HOSTS", Type: "StringList"}},
+				Environments: map[string]Environment{
+					"dev": {Prefix: "/dev"},
+					"prod": {
+						Prefix:      "/prod",
+						DefaultType: "SecureString",
+						Types:       []KeyType{{Key: "HOSTS", Type: "String"}},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName string
+		key             string
+		expectedType    string
+	}{
+		{"dev", "API_KEY", "SecureString"},
+		{"dev", "HOSTS", "StringList"},
+		{"dev", "OTHER", "String"},
+		{"prod", "HOSTS", "String"},
+		{"prod", "OTHER", "SecureString"},
+	}
+
+	for _, tt := range tests {
+		types, err := config.GetParameterTypes("project1", tt.environmentName)
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if got := types.TypeOf(tt.key); got != tt.expectedType {
+			t.Errorf("expected type %s for %s in %s, but got %s", tt.expectedType, tt.key, tt.environmentName, got)
+		}
+	}
+
+	if got := (ParameterTypes{}).TypeOf("ANY"); got != "SecureString" {
+		t.Errorf("expected SecureString by default, but got %s", got)
+	}
+}
+
+func TestGetKMSKeyID(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix:   "/project1",
+				KMSKeyID: "alias/project1",
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", KMSKeyID: "alias/project1-prod"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName string
+		expectedKeyID   string
+	}{
+		{"dev", "alias/project1"},
+		{"prod", "alias/project1-prod"},
+	}
+
+	for _, tt := range tests {
+		keyID, err := config.GetKMSKeyID("project1", tt.environmentName)
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if keyID != tt.expectedKeyID {
+			t.Errorf("expected kms key %s, but got %s", tt.expectedKeyID, keyID)
+		}
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix: "/project1",
+				Tags:   []Tag{{Key: "team", Value: "payments"}, {Key: "cost-center", Value: "1234"}},
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", Tags: []Tag{{Key: "cost-center", Value: "5678"}, {Key: "tier", Value: "critical"}}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName string
+		expectedTags    map[string]string
+	}{
+		{"dev", map[string]string{"team": "payments", "cost-center": "1234"}},
+		{"prod", map[string]string{"team": "payments", "cost-center": "5678", "tier": "critical"}},
+	}
+
+	for _, tt := range tests {
+		tags, err := config.GetTags("project1", tt.environmentName)
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if !reflect.DeepEqual(tags, tt.expectedTags) {
+			t.Errorf("expected tags %v, but got %v", tt.expectedTags, tags)
+		}
+	}
+}
+
+func TestGetTier(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix: "/project1",
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", Tier: "advanced"},
+				},
+			},
+			"project2": {
+				Prefix: "/project2",
+				Tier:   "intelligent",
+				Environments: map[string]Environment{
+					"dev": {Prefix: "/dev"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		projectName     string
+		environmentName string
+		expectedTier    string
+	}{
+		{"project1", "dev", "standard"},
+		{"project1", "prod", "advanced"},
+		{"project2", "dev", "intelligent"},
+	}
+
+	for _, tt := range tests {
+		tier, err := config.GetTier(tt.projectName, tt.environmentName)
+		if err != nil {
+			t.Errorf("unexpected error for %s/%s: %v", tt.projectName, tt.environmentName, err)
+		} else if tier != tt.expectedTier {
+			t.Errorf("expected tier %s, but got %s", tt.expectedTier, tier)
+		}
+	}
+}
+
+func TestGetTimeouts(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix:  "/project1",
+				Timeout: "2m",
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", Timeout: "10m", CallTimeout: "20s"},
+					"bad":  {Prefix: "/bad", CallTimeout: "soon"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName  string
+		expectedTimeouts Timeouts
+		expectError      bool
+	}{
+		{"dev", Timeouts{Total: 2 * time.Minute}, false},
+		{"prod", Timeouts{Total: 10 * time.Minute, Call: 20 * time.Second}, false},
+		{"bad", Timeouts{}, true},
+	}
+
+	for _, tt := range tests {
+		timeouts, err := config.GetTimeouts("project1", tt.environmentName)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("expected error for environment %s, but got none", tt.environmentName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if timeouts != tt.expectedTimeouts {
+			t.Errorf("expected timeouts %+v, but got %+v", tt.expectedTimeouts, timeouts)
+		}
+	}
+}
+
+func TestGetAWS(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"billing": {
+				Prefix:  "/billing",
+				Region:  "eu-west-1",
+				Profile: "billing",
+				Environments: map[string]Environment{
+					"dev":   {Prefix: "/dev"},
+					"local": {Prefix: "/local", EndpointURL: "http://localhost:4566"},
+					"prod": {
+						Prefix:      "/prod",
+						Region:      "eu-central-1",
+						RoleARN:     "arn:aws:iam::123456789012:role/ime",
+						ExternalID:  "ext-1",
+						SessionName: "ime-prod",
+					},
+					"bad": {Prefix: "/bad", ExternalID: "ext-1"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName string
+		expectedAWS     AWS
+		expectError     bool
+	}{
+		{"dev", AWS{Region: "eu-west-1", Profile: "billing"}, false},
+		{"local", AWS{Region: "eu-west-1", Profile: "billing", EndpointURL: "http://localhost:4566"}, false},
+		{"prod", AWS{
+			Region:      "eu-central-1",
+			Profile:     "billing",
+			RoleARN:     "arn:aws:iam::123456789012:role/ime",
+			ExternalID:  "ext-1",
+			SessionName: "ime-prod",
+		}, false},
+		{"bad", AWS{}, true},
+	}
+
+	for _, tt := range tests {
+		aws, err := config.GetAWS("billing", tt.environmentName)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("expected error for environment %s, but got none", tt.environmentName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if aws != tt.expectedAWS {
+			t.Errorf("expected aws settings %+v, but got %+v", tt.expectedAWS, aws)
+		}
+	}
+}
+
+func TestGetPolicies(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix:   "/project1",
+				Policies: PolicySettings{NotifyIfUnchangedFor: "90d"},
+				Environments: map[string]Environment{
+					"dev": {Prefix: "/dev"},
+					"vendor": {
+						Prefix:   "/vendor",
+						Policies: PolicySettings{ExpiresAfter: "30d"},
+						KeyPolicies: []KeyPolicySettings{
+							{Key: "VENDOR_TOKEN", PolicySettings: PolicySettings{ExpiresAfter: "7d", NotifyBeforeExpiry: "12h"}},
+						},
+					},
+					"bad": {Prefix: "/bad", Policies: PolicySettings{ExpiresAfter: "2w"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName  string
+		expectedPolicies ParameterPolicies
+		expectError      bool
+	}{
+		{"dev", ParameterPolicies{
+			Default: Policies{NotifyIfUnchangedFor: 90 * 24 * time.Hour},
+			Keys:    map[string]Policies{},
+		}, false},
+		{"vendor", ParameterPolicies{
+			Default: Policies{ExpiresAfter: 30 * 24 * time.Hour, NotifyIfUnchangedFor: 90 * 24 * time.Hour},
+			Keys: map[string]Policies{
+				"VENDOR_TOKEN": {ExpiresAfter: 7 * 24 * time.Hour, NotifyBeforeExpiry: 12 * time.Hour, NotifyIfUnchangedFor: 90 * 24 * time.Hour},
+			},
+		}, false},
+		{"bad", ParameterPolicies{}, true},
+	}
+
+	for _, tt := range tests {
+		policies, err := config.GetPolicies("project1", tt.environmentName)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("expected error for environment %s, but got none", tt.environmentName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if !reflect.DeepEqual(policies, tt.expectedPolicies) {
+			t.Errorf("expected policies %+v, but got %+v", tt.expectedPolicies, policies)
+		}
+	}
+}
+
+func TestGetKeys(t *testing.T) {
+	config := &Config{
+		GlobalPrefix: "/global",
+		Projects: map[string]Project{
+			"project1": {
+				Prefix: "/project1",
+				Keys:   []string{"DATABASE_URL"},
+				Environments: map[string]Environment{
+					"dev":  {Prefix: "/dev"},
+					"prod": {Prefix: "/prod", Keys: []string{"API_KEY", "API_URL"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		environmentName string
+		expectedKeys    []string
+	}{
+		{"dev", []string{"DATABASE_URL"}},
+		{"prod", []string{"API_KEY", "API_URL"}},
+	}
+
+	for _, tt := range tests {
+		keys, err := config.GetKeys("project1", tt.environmentName)
+		if err != nil {
+			t.Errorf("unexpected error for environment %s: %v", tt.environmentName, err)
+		} else if !reflect.DeepEqual(keys, tt.expectedKeys) {
+			t.Errorf("expected keys %v, but got %v", tt.expectedKeys, keys)
+		}
+	}
+}
+
 func TestGetResolvedLocalPath(t *testing.T) {
 	os.Setenv("TEST_PATH", "/test/path")
 	env := &Environment{
@@ -301,3 +805,123 @@ projects:
 		t.Errorf("expected environment prefix %s, but got %s", expectedEnvPrefix, env.Prefix)
 	}
 }
+
+// loadYAML loads content as the config through viper, as ime.yaml is.
+func loadYAML(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	t.Cleanup(viper.Reset)
+
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	return LoadConfig()
+}
+
+func TestLoadConfigTypes(t *testing.T) {
+	config, err := loadYAML(t, `
+global_prefix: /global
+projects:
+  project1:
+    prefix: /project1
+    types:
+      - key: FEATURE_FLAGS
+        type: StringList
+    environments:
+      dev:
+        prefix: /dev
+        types:
+          - key: ApiToken
+            type: String
+`)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	types, err := config.GetParameterTypes("project1", "dev")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := types.TypeOf("FEATURE_FLAGS"); got != "StringList" {
+		t.Errorf("expected FEATURE_FLAGS to be StringList, but got %s", got)
+	}
+	if got := types.TypeOf("ApiToken"); got != "String" {
+		t.Errorf("expected ApiToken to be String, but got %s", got)
+	}
+
+	_, err = loadYAML(t, `
+global_prefix: /global
+projects:
+  project1:
+    prefix: /project1
+    types:
+      - key: HOSTS
+        type: StringList
+      - key: HOSTS
+        type: String
+`)
+	if err == nil {
+		t.Errorf("expected error for a key typed twice, but got none")
+	}
+}
+
+func TestLoadConfigKeyPolicies(t *testing.T) {
+	config, err := loadYAML(t, `
+global_prefix: /global
+projects:
+  project1:
+    prefix: /project1
+    policies:
+      expires_after: 30d
+    environments:
+      vendor:
+        prefix: /vendor
+        key_policies:
+          - key: VENDOR_TOKEN
+            expires_after: 7d
+            notify_before_expiry: 12h
+`)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	policies, err := config.GetPolicies("project1", "vendor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Policies{ExpiresAfter: 7 * 24 * time.Hour, NotifyBeforeExpiry: 12 * time.Hour}
+	if got, ok := policies.Keys["VENDOR_TOKEN"]; !ok || got != expected {
+		t.Errorf("expected VENDOR_TOKEN policies %+v, but got %+v", expected, policies.Keys)
+	}
+}
+
+func TestLoadConfigTags(t *testing.T) {
+	config, err := loadYAML(t, `
+global_prefix: /global
+projects:
+  project1:
+    prefix: /project1
+    tags:
+      - key: CostCenter
+        value: "1234"
+    environments:
+      prod:
+        prefix: /prod
+        tags:
+          - key: DataClassification
+            value: Restricted
+`)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	tags, err := config.GetTags("project1", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"CostCenter": "1234", "DataClassification": "Restricted"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected tags %v, but got %v", expected, tags)
+	}
+}
@@ -0,0 +1,118 @@
+package environment
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Baseline records a hash of every value as it was the last time the env file
+// and the parameter store agreed. The hashes are keyed with a random salt kept
+// in the baseline, so a guessed value cannot be checked against them without
+// the file. The baseline sits next to the env file and should be kept as
+// private as it is.
+type Baseline struct {
+	Path   string            `json:"-"`
+	Salt   string            `json:"salt"`
+	Hashes map[string]string `json:"hashes"`
+}
+
+// BaselinePath returns the path of the baseline kept next to an env file,
+// hidden like the env file or made so: .env becomes .env.ime-base and
+// prod.env becomes .prod.env.ime-base.
+func BaselinePath(envPath string) string {
+	dir, file := filepath.Split(envPath)
+	if !strings.HasPrefix(file, ".") {
+		file = "." + file
+	}
+	return filepath.Join(dir, file+".ime-base")
+}
+
+func NewBaselineForEnvFile(ef *EnvFile) *Baseline {
+	return &Baseline{
+		Path:   BaselinePath(ef.Path),
+		Hashes: make(map[string]string),
+	}
+}
+
+// Load reads the baseline from Path. A missing baseline is not an error, it
+// just means the env file was never synced.
+func (b *Baseline) Load() error {
+	data, err := os.ReadFile(b.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read baseline %s: %w", b.Path, err)
+	}
+
+	if err := json.Unmarshal(data, b); err != nil {
+		return fmt.Errorf("unable to parse baseline %s: %w", b.Path, err)
+	}
+	if b.Hashes == nil {
+		b.Hashes = make(map[string]string)
+	}
+	return nil
+}
+
+func (b *Baseline) Save() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode baseline: %w", err)
+	}
+
+	if err := os.WriteFile(b.Path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write baseline %s: %w", b.Path, err)
+	}
+	return nil
+}
+
+func (b *Baseline) Set(key, value string) {
+	if b.Salt == "" {
+		b.Salt = newSalt()
+	}
+	b.Hashes[key] = b.hash(value)
+}
+
+func (b *Baseline) Delete(key string) {
+	delete(b.Hashes, key)
+}
+
+// Matches reports whether a value, or its absence, is what the baseline
+// recorded for key.
+func (b *Baseline) Matches(key, value string, present bool) bool {
+	hash, recorded := b.Hashes[key]
+	if !recorded || !present {
+		return recorded == present
+	}
+	return hmac.Equal([]byte(hash), []byte(b.hash(value)))
+}
+
+func (b *Baseline) hash(value string) string {
+	mac := hmac.New(sha256.New, []byte(b.Salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newSalt() string {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(fmt.Sprintf("unable to generate baseline salt: %v", err))
+	}
+	return hex.EncodeToString(salt)
+}
+
+// HashValue returns the unsalted hash of value, short prefixes of which stand
+// in for values in output.
+func HashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,274 @@
+package environment
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// line is a single line of an env file. Comments and blank lines have an
+// empty key and are kept only so the file can be written back as it was.
+type line struct {
+	raw string
+	key string
+}
+
+type EnvFile struct {
+	Path  string
+	Vars  map[string]string
+	lines []line
+
+	// annotations holds the "# ime:name=value" comments found directly above
+	// each key.
+	annotations map[string]map[string]string
+}
+
+func NewEnvFileFromPath(path string) *EnvFile {
+	return &EnvFile{
+		Path:        path,
+		Vars:        make(map[string]string),
+		annotations: make(map[string]map[string]string),
+	}
+}
+
+// LoadEnvFile reads the env file at Path, replacing anything loaded before.
+func (e *EnvFile) LoadEnvFile() error {
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return fmt.Errorf("unable to open env file %s: %w", e.Path, err)
+	}
+	defer f.Close()
+
+	e.Vars = make(map[string]string)
+	e.lines = nil
+	e.annotations = make(map[string]map[string]string)
+
+	// Annotations apply to the next key, unless a blank line comes first.
+	var pending map[string]string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		raw := scanner.Text()
+
+		key, value, ok, err := parseLine(raw)
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", e.Path, n, err)
+		}
+
+		switch {
+		case ok:
+			e.Vars[key] = value
+			if pending != nil {
+				e.annotations[key] = pending
+				pending = nil
+			}
+		case strings.TrimSpace(raw) == "":
+			pending = nil
+		default:
+			pending = parseAnnotations(raw, pending)
+		}
+		e.lines = append(e.lines, line{raw: raw, key: key})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read env file %s: %w", e.Path, err)
+	}
+	return nil
+}
+
+// Keys returns the variable names in the order they first appear in the file.
+func (e *EnvFile) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, l := range e.lines {
+		if l.key == "" || seen[l.key] {
+			continue
+		}
+		seen[l.key] = true
+		keys = append(keys, l.key)
+	}
+	return keys
+}
+
+// Annotation returns the value of an "# ime:name=value" comment written on
+// the lines directly above key.
+func (e *EnvFile) Annotation(key, name string) (string, bool) {
+	value, ok := e.annotations[key][name]
+	return value, ok
+}
+
+// Set updates every line holding key, or appends a new line when the key is
+// not in the file yet.
+func (e *EnvFile) Set(key, value string) {
+	e.Vars[key] = value
+
+	found := false
+	for i, l := range e.lines {
+		if l.key != key {
+			continue
+		}
+		found = true
+		e.lines[i].raw = formatLine(key, value, strings.HasPrefix(strings.TrimSpace(l.raw), "export "))
+	}
+
+	if !found {
+		e.lines = append(e.lines, line{raw: formatLine(key, value, false), key: key})
+	}
+}
+
+// Delete removes every line holding key.
+func (e *EnvFile) Delete(key string) {
+	delete(e.Vars, key)
+
+	lines := e.lines[:0]
+	for _, l := range e.lines {
+		if l.key != key {
+			lines = append(lines, l)
+		}
+	}
+	e.lines = lines
+}
+
+// WriteEnvFile writes the file back to Path. Lines that were not changed
+// through Set or Delete are written exactly as they were read.
+func (e *EnvFile) WriteEnvFile() error {
+	var b strings.Builder
+	for _, l := range e.lines {
+		b.WriteString(l.raw)
+		b.WriteString("\n")
+	}
+
+	if err := os.WriteFile(e.Path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("unable to write env file %s: %w", e.Path, err)
+	}
+	return nil
+}
+
+func formatLine(key, value string, export bool) string {
+	prefix := ""
+	if export {
+		prefix = "export "
+	}
+	return fmt.Sprintf("%s%s=%s", prefix, key, quoteValue(value))
+}
+
+func quoteValue(value string) string {
+	if !strings.ContainsAny(value, " \t\r\n#'\"\\") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// parseAnnotations adds the ime:name=value words of a comment line to found.
+func parseAnnotations(raw string, found map[string]string) map[string]string {
+	comment := strings.TrimPrefix(strings.TrimSpace(raw), "#")
+	for _, word := range strings.Fields(comment) {
+		annotation, ok := strings.CutPrefix(word, "ime:")
+		if !ok {
+			continue
+		}
+		name, value, ok := strings.Cut(annotation, "=")
+		if !ok {
+			continue
+		}
+		if found == nil {
+			found = make(map[string]string)
+		}
+		found[name] = value
+	}
+	return found
+}
+
+func parseLine(raw string) (string, string, bool, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false, nil
+	}
+
+	trimmed = strings.TrimPrefix(trimmed, "export ")
+
+	key, value, found := strings.Cut(trimmed, "=")
+	if !found {
+		return "", "", false, fmt.Errorf("expected KEY=VALUE, got %q", raw)
+	}
+
+	key = strings.TrimSpace(key)
+	if !isValidKey(key) {
+		return "", "", false, fmt.Errorf("invalid variable name %q", key)
+	}
+
+	value, err := parseValue(strings.TrimSpace(value))
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return key, value, true, nil
+}
+
+func parseValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch value[0] {
+	case '"', '\'':
+		quote := value[0]
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		// Only a comment may follow the closing quote.
+		if rest := strings.TrimSpace(value[end+1:]); rest != "" && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected text after closing quote: %s", rest)
+		}
+		if quote == '\'' {
+			return value[1:end], nil
+		}
+		return unescape(value[1:end]), nil
+	}
+
+	// Unquoted values may carry a trailing comment separated by whitespace.
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// closingQuote returns the index of the quote that closes the one value starts
+// with, or -1. Inside double quotes a backslash escapes the next character.
+func closingQuote(value string) int {
+	quote := value[0]
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+	return r.Replace(s)
+}
+
+func isValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,239 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempEnvFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write test env file: %v", err)
+	}
+	return path
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	content := `# database settings
+DB_HOST=localhost
+export DB_PORT=5432
+
+DB_PASSWORD="p@ss word"
+SINGLE='$NOT_EXPANDED'
+ESCAPED="line1\nline2"
+TRAILING=value # comment
+QUOTED_COMMENT="abc" # the "prod" value
+QUOTED_ESCAPE="say \"hi\"" # greeting
+EMPTY=
+`
+	ef := NewEnvFileFromPath(writeTempEnvFile(t, content))
+	if err := ef.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+
+	expected := map[string]string{
+		"DB_HOST":        "localhost",
+		"DB_PORT":        "5432",
+		"DB_PASSWORD":    "p@ss word",
+		"SINGLE":         "$NOT_EXPANDED",
+		"ESCAPED":        "line1\nline2",
+		"TRAILING":       "value",
+		"QUOTED_COMMENT": "abc",
+		"QUOTED_ESCAPE":  `say "hi"`,
+		"EMPTY":          "",
+	}
+
+	if len(ef.Vars) != len(expected) {
+		t.Errorf("expected %d vars, but got %d", len(expected), len(ef.Vars))
+	}
+	for k, v := range expected {
+		if ef.Vars[k] != v {
+			t.Errorf("expected %s=%q, but got %q", k, v, ef.Vars[k])
+		}
+	}
+
+	keys := ef.Keys()
+	if keys[0] != "DB_HOST" || keys[len(keys)-1] != "EMPTY" {
+		t.Errorf("expected keys in file order, but got %v", keys)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	content := `# ime:type=String
+FEATURE_FLAGS=a,b
+# plain comment
+# ime:type=StringList
+HOSTS=a,b
+# ime:type=String
+
+SECRET=x
+`
+	ef := NewEnvFileFromPath(writeTempEnvFile(t, content))
+	if err := ef.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+		found    bool
+	}{
+		{"FEATURE_FLAGS", "String", true},
+		{"HOSTS", "StringList", true},
+		{"SECRET", "", false},
+	}
+
+	for _, tt := range tests {
+		value, found := ef.Annotation(tt.key, "type")
+		if value != tt.expected || found != tt.found {
+			t.Errorf("expected type annotation %q (%v) for %s, but got %q (%v)", tt.expected, tt.found, tt.key, value, found)
+		}
+	}
+}
+
+func TestLoadEnvFileErrors(t *testing.T) {
+	tests := []string{
+		"NOT A VALID LINE\n",
+		"1KEY=value\n",
+		"KEY=\"unterminated\n",
+		"KEY=\"abc\"def\n",
+	}
+
+	for _, content := range tests {
+		ef := NewEnvFileFromPath(writeTempEnvFile(t, content))
+		if err := ef.LoadEnvFile(); err == nil {
+			t.Errorf("expected error for %q, but got none", content)
+		}
+	}
+}
+
+func TestWriteEnvFilePreservesLayout(t *testing.T) {
+	content := `# header comment
+
+export FIRST=1
+# about second
+SECOND=2
+
+THIRD=3
+`
+	path := writeTempEnvFile(t, content)
+	ef := NewEnvFileFromPath(path)
+	if err := ef.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+
+	ef.Set("FIRST", "one")
+	ef.Delete("THIRD")
+	ef.Set("FOURTH", "has spaces")
+
+	if err := ef.WriteEnvFile(); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read env file: %v", err)
+	}
+
+	expected := `# header comment
+
+export FIRST=one
+# about second
+SECOND=2
+
+FOURTH="has spaces"
+`
+	if string(data) != expected {
+		t.Errorf("expected file:\n%s\nbut got:\n%s", expected, string(data))
+	}
+}
+
+func TestSetRoundTrip(t *testing.T) {
+	values := []string{"plain", "with space", `quote"d`, `back\slash`, "multi\nline", "#hash", ""}
+
+	path := writeTempEnvFile(t, "")
+	ef := NewEnvFileFromPath(path)
+	for i, v := range values {
+		ef.Set(string(rune('A'+i)), v)
+	}
+	if err := ef.WriteEnvFile(); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	loaded := NewEnvFileFromPath(path)
+	if err := loaded.LoadEnvFile(); err != nil {
+		t.Fatalf("failed to load env file: %v", err)
+	}
+	for i, v := range values {
+		key := string(rune('A' + i))
+		if loaded.Vars[key] != v {
+			t.Errorf("expected %s=%q, but got %q", key, v, loaded.Vars[key])
+		}
+	}
+}
+
+func TestBaselinePath(t *testing.T) {
+	tests := []struct {
+		envPath  string
+		expected string
+	}{
+		{".env", ".env.ime-base"},
+		{"config/.env", filepath.Join("config", ".env.ime-base")},
+		{"config/prod.env", filepath.Join("config", ".prod.env.ime-base")},
+	}
+
+	for _, tt := range tests {
+		if got := BaselinePath(tt.envPath); got != tt.expected {
+			t.Errorf("expected baseline %s for %s, but got %s", tt.expected, tt.envPath, got)
+		}
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	ef := NewEnvFileFromPath(filepath.Join(t.TempDir(), ".env"))
+
+	base := NewBaselineForEnvFile(ef)
+	if err := base.Load(); err != nil {
+		t.Fatalf("unexpected error loading missing baseline: %v", err)
+	}
+
+	base.Set("KEY", "value")
+	if err := base.Save(); err != nil {
+		t.Fatalf("failed to save baseline: %v", err)
+	}
+
+	loaded := NewBaselineForEnvFile(ef)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("failed to load baseline: %v", err)
+	}
+	if loaded.Salt == "" || loaded.Hashes["KEY"] == HashValue("value") {
+		t.Errorf("expected a salted hash, but got %v", loaded.Hashes)
+	}
+
+	other := NewBaselineForEnvFile(NewEnvFileFromPath(filepath.Join(t.TempDir(), ".env")))
+	other.Set("KEY", "value")
+	if other.Hashes["KEY"] == loaded.Hashes["KEY"] {
+		t.Errorf("expected each baseline to use its own salt")
+	}
+
+	tests := []struct {
+		key      string
+		value    string
+		present  bool
+		expected bool
+	}{
+		{"KEY", "value", true, true},
+		{"KEY", "other", true, false},
+		{"KEY", "", false, false},
+		{"MISSING", "", false, true},
+		{"MISSING", "value", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := loaded.Matches(tt.key, tt.value, tt.present); got != tt.expected {
+			t.Errorf("expected Matches(%s, %q, %v) to be %v, but got %v", tt.key, tt.value, tt.present, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package paramstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultSessionName names the session ime opens when it assumes a role.
+const DefaultSessionName = "ime"
+
+// AWSOptions select the account and region a backend talks to. Empty fields
+// leave the AWS SDK's defaults alone.
+type AWSOptions struct {
+	Region  string
+	Profile string
+	// RoleARN is assumed with the credentials from Profile, or the ambient
+	// credentials, before any call.
+	RoleARN    string
+	ExternalID string
+	// SessionName names the assumed role session. Empty means
+	// DefaultSessionName.
+	SessionName string
+	// EndpointURL replaces the regional endpoint, for example with a
+	// LocalStack URL.
+	EndpointURL string
+}
+
+// loadAWSConfig loads the AWS SDK configuration for opts. Clients built from
+// it make a single attempt per call, as callPaced owns retries.
+func loadAWSConfig(ctx context.Context, opts AWSOptions) (aws.Config, error) {
+	var loadOpts []func(*config.LoadOptions) error
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	if opts.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(opts.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("unable to load AWS SDK config, %w", err)
+	}
+
+	if opts.RoleARN == "" {
+		cfg.Retryer = newRetryer
+		return cfg, nil
+	}
+
+	sessionName := opts.SessionName
+	if sessionName == "" {
+		sessionName = DefaultSessionName
+	}
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if opts.ExternalID != "" {
+			o.ExternalID = aws.String(opts.ExternalID)
+		}
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	cfg.Retryer = newRetryer
+	return cfg, nil
+}
+
+// newRetryer returns the SDK's standard retryer without its retry quota and
+// without retries for the throttles isThrottle recognises. callPaced backs
+// those off itself; the SDK would add its own backoff on top and, once its
+// quota ran out, fail calls with a ratelimit.QuotaExceededError that isThrottle
+// does not recognise. Server errors, connection resets and timeouts are still
+// retried by the SDK.
+func newRetryer() aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.RateLimiter = ratelimit.None
+		o.Retryables = append([]retry.IsErrorRetryable{
+			retry.IsErrorRetryableFunc(func(err error) aws.Ternary {
+				if isThrottle(err) {
+					return aws.FalseTernary
+				}
+				return aws.UnknownTernary
+			}),
+		}, o.Retryables...)
+	})
+}
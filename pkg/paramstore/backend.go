@@ -0,0 +1,193 @@
+package paramstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const BackendSSM = "ssm"
+
+// Backend stores the parameters of a single environment path. Keys are the
+// names relative to the path, the same names used in env files. Methods that
+// reach the store stop when their context is done.
+type Backend interface {
+	// Path is the environment path the backend reads and writes under.
+	Path() string
+	// FormatParamName returns the full name the backend stores key under.
+	FormatParamName(key string) string
+
+	// GetParameters returns every parameter under the path.
+	GetParameters(ctx context.Context) (map[string]string, error)
+	// GetParametersByName returns the given keys, and the keys that do not exist.
+	GetParametersByName(ctx context.Context, keys []string) (map[string]string, []string, error)
+	PutParameter(ctx context.Context, param Parameter, overwrite bool) (int64, error)
+	// PutParameters writes params and returns the version written per key.
+	// Keys that failed are reported together in a *PutError.
+	PutParameters(ctx context.Context, params []Parameter, overwrite bool) (map[string]int64, error)
+	// Validate reports every parameter the backend would refuse to write.
+	Validate(params []Parameter) error
+	// DeleteParameters returns the keys that were deleted and why the others were not.
+	DeleteParameters(ctx context.Context, keys []string) ([]string, map[string]error)
+	// GetParameterHistory returns every stored version of key, oldest first.
+	GetParameterHistory(ctx context.Context, key string) ([]ParameterVersion, error)
+	// LabelParameter attaches label to the version of key last read, or to
+	// its latest version when key has not been read.
+	LabelParameter(ctx context.Context, key, label string) error
+	// ParameterTypes returns the type of every parameter read or written so far.
+	ParameterTypes() map[string]string
+}
+
+// ChangeWriter is implemented by backends that write the creates, updates
+// and deletes of a push together. SecretStore does, so that a push makes one
+// new version of the secret rather than one per kind of change.
+type ChangeWriter interface {
+	// WriteChanges applies changes in one write. It returns the version
+	// written per created or updated key, the keys deleted and why every
+	// other key failed.
+	WriteChanges(ctx context.Context, changes Changes) (map[string]int64, []string, map[string]error)
+}
+
+// Changes are the writes of a push. Creates fail for keys that exist, while
+// Updates overwrite them.
+type Changes struct {
+	Creates []Parameter
+	Updates []Parameter
+	Deletes []string
+}
+
+// Parameter is a value to write and the type to store it as. An empty Type
+// means SecureString.
+type Parameter struct {
+	Key   string
+	Value string
+	Type  string
+}
+
+// PutError holds the reason for every key a PutParameters call failed to
+// write.
+type PutError struct {
+	Failed map[string]error
+}
+
+func (e *PutError) Error() string {
+	keys := make([]string, 0, len(e.Failed))
+	for k := range e.Failed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = fmt.Sprintf("%s: %s", k, e.Failed[k])
+	}
+	return fmt.Sprintf("%d parameter(s) failed:\n  %s", len(keys), strings.Join(lines, "\n  "))
+}
+
+type ParameterVersion struct {
+	Version      int64
+	Value        string
+	Type         string
+	LastModified time.Time
+	ModifiedBy   string
+	Labels       []string
+}
+
+// Options are the settings a backend is built with.
+type Options struct {
+	Path       string
+	KeyMapping KeyMapping
+	KMSKeyID   string
+	// Tier is standard, advanced or intelligent. Empty means standard.
+	Tier string
+	// Tags are put on every parameter the backend writes.
+	Tags map[string]string
+	// Policies are put on the parameters the backend writes.
+	Policies KeyPolicies
+	// Filter narrows what GetParameters returns.
+	Filter Filter
+	// CallTimeout bounds every single call to the store. Zero means
+	// DefaultCallTimeout.
+	CallTimeout time.Duration
+	// AWS selects the account and region the backend talks to.
+	AWS AWSOptions
+}
+
+// Filter narrows the parameters GetParameters returns. The zero Filter
+// returns everything under the path.
+type Filter struct {
+	// Tags keeps only parameters carrying every one of these tags.
+	Tags map[string]string
+	// Label keeps only parameters with a version carrying this label, and
+	// returns that version instead of the latest. A label nothing carries
+	// is an error rather than an empty result.
+	Label string
+}
+
+// NewBackend returns the backend of the given kind. An empty kind means SSM
+// Parameter Store. Secrets Manager ignores the tier and policies.
+func NewBackend(ctx context.Context, kind string, opts Options) (Backend, error) {
+	switch kind {
+	case "", BackendSSM:
+		ps, err := NewParamStore(ctx, opts.Path, opts.AWS)
+		if err != nil {
+			return nil, err
+		}
+		ps.KeyMapping = opts.KeyMapping
+		ps.KMSKeyID = opts.KMSKeyID
+		ps.Tier = opts.Tier
+		ps.Tags = opts.Tags
+		ps.Policies = opts.Policies
+		ps.Filter = opts.Filter
+		ps.CallTimeout = opts.CallTimeout
+		return ps, nil
+	case BackendSecretsManager:
+		ss, err := NewSecretStore(ctx, opts.Path, opts.AWS)
+		if err != nil {
+			return nil, err
+		}
+		ss.KeyMapping = opts.KeyMapping
+		ss.KMSKeyID = opts.KMSKeyID
+		ss.Tags = opts.Tags
+		ss.Filter = opts.Filter
+		ss.CallTimeout = opts.CallTimeout
+		return ss, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", kind)
+	}
+}
+
+const TypeStringList = "StringList"
+
+// FormatStringLists returns params with every StringList value, which SSM
+// returns comma-joined, rewritten as a JSON array when format is json.
+func FormatStringLists(b Backend, params map[string]string, format string) map[string]string {
+	if format != "json" {
+		return params
+	}
+
+	types := b.ParameterTypes()
+	formatted := make(map[string]string, len(params))
+	for k, v := range params {
+		if types[k] == TypeStringList {
+			items, _ := json.Marshal(strings.Split(v, ","))
+			v = string(items)
+		}
+		formatted[k] = v
+	}
+	return formatted
+}
+
+// NormalizeStringList turns a StringList value written as a JSON array, as
+// FormatStringLists does, back into the comma-joined form SSM stores. Other
+// values are returned unchanged.
+func NormalizeStringList(value string) string {
+	var items []string
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return value
+	}
+	return strings.Join(items, ",")
+}
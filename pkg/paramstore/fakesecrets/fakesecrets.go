@@ -0,0 +1,392 @@
+// Package fakesecrets is an in-memory stand-in for the Secrets Manager
+// client, for tests that exercise SecretStore without AWS. It mimics the
+// parts of Secrets Manager ime relies on: creating a secret once, new
+// versions on every put, the AWSCURRENT and AWSPREVIOUS staging labels,
+// moving labels between versions, and tags.
+package fakesecrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+const (
+	stageCurrent  = "AWSCURRENT"
+	stagePrevious = "AWSPREVIOUS"
+)
+
+// Version is one stored version of a secret.
+type Version struct {
+	ID      string
+	Value   string
+	Stages  []string
+	Created time.Time
+}
+
+func (v *Version) hasStage(stage string) bool {
+	for _, s := range v.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Version) removeStage(stage string) {
+	stages := v.Stages[:0]
+	for _, s := range v.Stages {
+		if s != stage {
+			stages = append(stages, s)
+		}
+	}
+	v.Stages = stages
+}
+
+type secret struct {
+	versions []*Version
+	tags     map[string]string
+	kmsKeyID string
+}
+
+func (s *secret) withStage(stage string) *Version {
+	for _, v := range s.versions {
+		if v.hasStage(stage) {
+			return v
+		}
+	}
+	return nil
+}
+
+type Client struct {
+	mu      sync.Mutex
+	secrets map[string]*secret
+	now     time.Time
+	ids     int
+
+	// Calls counts the calls made per operation name, e.g. "PutSecretValue".
+	Calls map[string]int
+	// MaxVersions, when set, prunes the oldest versions without a staging
+	// label once a secret holds more, as Secrets Manager does.
+	MaxVersions int
+}
+
+func New() *Client {
+	return &Client{
+		secrets: make(map[string]*secret),
+		now:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Calls:   make(map[string]int),
+	}
+}
+
+// Seed puts value directly, bypassing the API, as the current version of the
+// secret name, creating the secret when needed.
+func (c *Client) Seed(name, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.secrets[name]; !ok {
+		c.secrets[name] = &secret{tags: make(map[string]string)}
+	}
+	c.put(name, value, "")
+}
+
+// Versions returns every stored version of the secret name, oldest first.
+func (c *Client) Versions(name string) []Version {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.secrets[name]
+	if !ok {
+		return nil
+	}
+	versions := make([]Version, len(s.versions))
+	for i, v := range s.versions {
+		versions[i] = *v
+		versions[i].Stages = append([]string(nil), v.Stages...)
+	}
+	return versions
+}
+
+// Tags returns the tags on the secret name.
+func (c *Client) Tags(name string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.secrets[name]
+	if !ok {
+		return nil
+	}
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// put adds value as the new current version of an existing secret. An empty
+// id gets a generated one.
+func (c *Client) put(name, value, id string) *Version {
+	// Every write moves the clock so versions have distinct dates.
+	c.now = c.now.Add(time.Minute)
+	c.ids++
+
+	s := c.secrets[name]
+	if previous := s.withStage(stagePrevious); previous != nil {
+		previous.removeStage(stagePrevious)
+	}
+	if current := s.withStage(stageCurrent); current != nil {
+		current.removeStage(stageCurrent)
+		current.Stages = append(current.Stages, stagePrevious)
+	}
+
+	if id == "" {
+		id = fmt.Sprintf("00000000-0000-0000-0000-%012d", c.ids)
+	}
+	v := &Version{
+		ID:      id,
+		Value:   value,
+		Stages:  []string{stageCurrent},
+		Created: c.now,
+	}
+	s.versions = append(s.versions, v)
+	c.prune(s)
+	return v
+}
+
+func (c *Client) prune(s *secret) {
+	if c.MaxVersions <= 0 {
+		return
+	}
+	excess := len(s.versions) - c.MaxVersions
+	versions := s.versions[:0]
+	for _, v := range s.versions {
+		if excess > 0 && len(v.Stages) == 0 {
+			excess--
+			continue
+		}
+		versions = append(versions, v)
+	}
+	s.versions = versions
+}
+
+func (c *Client) call(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Calls[op]++
+	return nil
+}
+
+func (c *Client) find(id *string) (*secret, error) {
+	s, ok := c.secrets[aws.ToString(id)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Secrets Manager can't find the specified secret.")}
+	}
+	return s, nil
+}
+
+func (c *Client) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetSecretValue"); err != nil {
+		return nil, err
+	}
+
+	s, err := c.find(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	var v *Version
+	switch {
+	case params.VersionId != nil:
+		for _, candidate := range s.versions {
+			if candidate.ID == aws.ToString(params.VersionId) {
+				v = candidate
+			}
+		}
+		if v != nil && params.VersionStage != nil && !v.hasStage(aws.ToString(params.VersionStage)) {
+			v = nil
+		}
+	case params.VersionStage != nil:
+		v = s.withStage(aws.ToString(params.VersionStage))
+	default:
+		v = s.withStage(stageCurrent)
+	}
+	if v == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Secrets Manager can't find the specified secret value for the version.")}
+	}
+
+	return &secretsmanager.GetSecretValueOutput{
+		Name:          params.SecretId,
+		SecretString:  aws.String(v.Value),
+		VersionId:     aws.String(v.ID),
+		VersionStages: append([]string(nil), v.Stages...),
+		CreatedDate:   aws.Time(v.Created),
+	}, nil
+}
+
+func (c *Client) CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "CreateSecret"); err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.Name)
+	if _, ok := c.secrets[name]; ok {
+		return nil, &types.ResourceExistsException{Message: aws.String(fmt.Sprintf("The operation failed because the secret %s already exists.", name))}
+	}
+
+	s := &secret{tags: make(map[string]string), kmsKeyID: aws.ToString(params.KmsKeyId)}
+	for _, tag := range params.Tags {
+		s.tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	c.secrets[name] = s
+
+	v := c.put(name, aws.ToString(params.SecretString), aws.ToString(params.ClientRequestToken))
+	return &secretsmanager.CreateSecretOutput{Name: params.Name, VersionId: aws.String(v.ID)}, nil
+}
+
+func (c *Client) PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "PutSecretValue"); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.find(params.SecretId); err != nil {
+		return nil, err
+	}
+
+	v := c.put(aws.ToString(params.SecretId), aws.ToString(params.SecretString), aws.ToString(params.ClientRequestToken))
+	return &secretsmanager.PutSecretValueOutput{
+		Name:          params.SecretId,
+		VersionId:     aws.String(v.ID),
+		VersionStages: append([]string(nil), v.Stages...),
+	}, nil
+}
+
+func (c *Client) DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DescribeSecret"); err != nil {
+		return nil, err
+	}
+
+	s, err := c.find(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(s.tags))
+	for k := range s.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := &secretsmanager.DescribeSecretOutput{
+		Name:               params.SecretId,
+		VersionIdsToStages: make(map[string][]string),
+	}
+	if s.kmsKeyID != "" {
+		out.KmsKeyId = aws.String(s.kmsKeyID)
+	}
+	for _, k := range keys {
+		out.Tags = append(out.Tags, types.Tag{Key: aws.String(k), Value: aws.String(s.tags[k])})
+	}
+	// Like Secrets Manager, only versions with a staging label are listed.
+	for _, v := range s.versions {
+		if len(v.Stages) > 0 {
+			out.VersionIdsToStages[v.ID] = append([]string(nil), v.Stages...)
+		}
+	}
+	return out, nil
+}
+
+func (c *Client) ListSecretVersionIds(ctx context.Context, params *secretsmanager.ListSecretVersionIdsInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretVersionIdsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "ListSecretVersionIds"); err != nil {
+		return nil, err
+	}
+
+	s, err := c.find(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &secretsmanager.ListSecretVersionIdsOutput{Name: params.SecretId}
+	for _, v := range s.versions {
+		if len(v.Stages) == 0 && !aws.ToBool(params.IncludeDeprecated) {
+			continue
+		}
+		out.Versions = append(out.Versions, types.SecretVersionsListEntry{
+			VersionId:     aws.String(v.ID),
+			VersionStages: append([]string(nil), v.Stages...),
+			CreatedDate:   aws.Time(v.Created),
+		})
+	}
+	return out, nil
+}
+
+func (c *Client) UpdateSecretVersionStage(ctx context.Context, params *secretsmanager.UpdateSecretVersionStageInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.UpdateSecretVersionStageOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "UpdateSecretVersionStage"); err != nil {
+		return nil, err
+	}
+
+	s, err := c.find(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	stage := aws.ToString(params.VersionStage)
+	var target *Version
+	for _, v := range s.versions {
+		if v.ID == aws.ToString(params.MoveToVersionId) {
+			target = v
+		}
+	}
+	if target == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Secrets Manager can't find the specified version.")}
+	}
+
+	// A label held by another version is only moved when the request names
+	// that version.
+	if holder := s.withStage(stage); holder != nil && holder != target {
+		if holder.ID != aws.ToString(params.RemoveFromVersionId) {
+			return nil, &types.InvalidParameterException{Message: aws.String(fmt.Sprintf("The staging label %s is currently attached to version %s, specify it in RemoveFromVersionId.", stage, holder.ID))}
+		}
+		holder.removeStage(stage)
+	}
+	if !target.hasStage(stage) {
+		target.Stages = append(target.Stages, stage)
+	}
+	return &secretsmanager.UpdateSecretVersionStageOutput{Name: params.SecretId}, nil
+}
+
+func (c *Client) TagResource(ctx context.Context, params *secretsmanager.TagResourceInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.TagResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "TagResource"); err != nil {
+		return nil, err
+	}
+
+	s, err := c.find(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range params.Tags {
+		s.tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return &secretsmanager.TagResourceOutput{}, nil
+}
@@ -0,0 +1,733 @@
+// Package fakessm is an in-memory stand-in for the SSM client, for tests that
+// exercise ParamStore without AWS. It mimics the parts of SSM ime relies on:
+// paging with NextToken, Overwrite and ParameterAlreadyExists, version numbers,
+// history, tags, labels, parameter policies and the 10 name limit of the batch
+// calls.
+package fakessm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// User is reported as the LastModifiedUser of every version.
+	User = "arn:aws:iam::123456789012:user/fakessm"
+
+	maxBatchNames      = 10
+	maxPathResults     = 10
+	maxHistoryResults  = 50
+	maxDescribeResults = 50
+	maxVersionLabels   = 10
+	maxStandardValue   = 4096
+	maxAdvancedValue   = 8192
+	maxHierarchyLevels = 15
+)
+
+// Version is one stored version of a parameter, as it was put.
+type Version struct {
+	Value string
+	Type  types.ParameterType
+	KeyID string
+	Tier  types.ParameterTier
+	// Policies is the policy JSON the version was put with.
+	Policies     string
+	Version      int64
+	LastModified time.Time
+	Labels       []string
+}
+
+type parameter struct {
+	versions []Version
+	tags     map[string]string
+}
+
+func (p *parameter) latest() Version {
+	return p.versions[len(p.versions)-1]
+}
+
+type Client struct {
+	mu         sync.Mutex
+	parameters map[string]*parameter
+	now        time.Time
+
+	// Calls counts the calls made per operation name, e.g. "PutParameter".
+	Calls map[string]int
+	// throttled is how many more calls per operation name fail with a
+	// ThrottlingException.
+	throttled map[string]int
+}
+
+func New() *Client {
+	return &Client{
+		parameters: make(map[string]*parameter),
+		now:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Calls:      make(map[string]int),
+		throttled:  make(map[string]int),
+	}
+}
+
+// Seed puts values directly, bypassing the API, as version 1 String parameters
+// or as a new version of an existing one.
+func (c *Client) Seed(values map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, value := range values {
+		c.put(name, Version{Value: value, Type: types.ParameterTypeString, Tier: types.ParameterTierStandard})
+	}
+}
+
+// Versions returns every stored version of name, oldest first.
+func (c *Client) Versions(name string) []Version {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[name]
+	if !ok {
+		return nil
+	}
+	return append([]Version(nil), p.versions...)
+}
+
+// Tags returns the tags of name.
+func (c *Client) Tags(name string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[name]
+	if !ok {
+		return nil
+	}
+	tags := make(map[string]string, len(p.tags))
+	for k, v := range p.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// Names returns the names of every stored parameter, sorted.
+func (c *Client) Names() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.sortedNames()
+}
+
+func (c *Client) put(name string, v Version) Version {
+	// Every write moves the clock so versions have distinct dates.
+	c.now = c.now.Add(time.Minute)
+	v.LastModified = c.now
+
+	p, ok := c.parameters[name]
+	if !ok {
+		p = &parameter{tags: make(map[string]string)}
+		c.parameters[name] = p
+	}
+	v.Version = int64(len(p.versions) + 1)
+	p.versions = append(p.versions, v)
+	return v
+}
+
+func (c *Client) sortedNames() []string {
+	names := make([]string, 0, len(c.parameters))
+	for name := range c.parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Throttle makes the next n calls of op fail with a ThrottlingException.
+func (c *Client) Throttle(op string, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.throttled[op] = n
+}
+
+// call records a call of op and fails it when ctx is done or op is being
+// throttled.
+func (c *Client) call(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Calls[op]++
+	if c.throttled[op] > 0 {
+		c.throttled[op]--
+		return &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded", Fault: smithy.FaultClient}
+	}
+	return nil
+}
+
+func toParameter(name string, v Version) types.Parameter {
+	return types.Parameter{
+		Name:             aws.String(name),
+		Value:            aws.String(v.Value),
+		Type:             v.Type,
+		Version:          v.Version,
+		LastModifiedDate: aws.Time(v.LastModified),
+		DataType:         aws.String("text"),
+	}
+}
+
+func validationError(format string, args ...any) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+// page returns the slice of items for a NextToken, which the fake encodes as
+// the offset of the next item.
+func page(total int, next *string, max *int32, limit int) (int, int, *string, error) {
+	start := 0
+	if next != nil && *next != "" {
+		n, err := strconv.Atoi(*next)
+		if err != nil || n < 0 || n > total {
+			return 0, 0, nil, &types.InvalidNextToken{Message: aws.String("The specified token isn't valid.")}
+		}
+		start = n
+	}
+
+	size := limit
+	if max != nil {
+		if *max < 1 || int(*max) > limit {
+			return 0, 0, nil, validationError("maxResults must be between 1 and %d", limit)
+		}
+		size = int(*max)
+	}
+
+	end := start + size
+	if end >= total {
+		return start, total, nil, nil
+	}
+	return start, end, aws.String(strconv.Itoa(end)), nil
+}
+
+func (c *Client) PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "PutParameter"); err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.Name)
+	if name == "" || params.Value == nil {
+		return nil, validationError("name and value are required")
+	}
+
+	existing, exists := c.parameters[name]
+	if exists && !aws.ToBool(params.Overwrite) {
+		return nil, &types.ParameterAlreadyExists{Message: aws.String("The parameter already exists. To overwrite this value, set the overwrite option in the request to true.")}
+	}
+	if len(params.Tags) > 0 && aws.ToBool(params.Overwrite) {
+		return nil, validationError("tags and overwrite can't be used together, tag an existing parameter with AddTagsToResource")
+	}
+
+	v := Version{
+		Value:    aws.ToString(params.Value),
+		Type:     params.Type,
+		KeyID:    aws.ToString(params.KeyId),
+		Policies: aws.ToString(params.Policies),
+	}
+	if v.Type == "" {
+		if !exists {
+			return nil, validationError("a type is required to create parameter %s", name)
+		}
+		v.Type = existing.latest().Type
+	}
+	if v.Type != types.ParameterTypeSecureString && v.KeyID != "" {
+		return nil, validationError("a KeyId can only be set for SecureString parameters")
+	}
+	if v.Type == types.ParameterTypeSecureString && v.KeyID == "" {
+		v.KeyID = "alias/aws/ssm"
+	}
+
+	if levels := len(strings.Split(strings.Trim(name, "/"), "/")); levels > maxHierarchyLevels {
+		return nil, &types.HierarchyLevelLimitExceededException{Message: aws.String(fmt.Sprintf("A parameter name can have at most %d levels.", maxHierarchyLevels))}
+	}
+
+	// Advanced parameters stay advanced, and intelligent tiering picks the
+	// cheapest tier the value fits in.
+	v.Tier = params.Tier
+	switch {
+	case exists && existing.latest().Tier == types.ParameterTierAdvanced:
+		if v.Tier == types.ParameterTierStandard {
+			return nil, validationError("parameter %s is advanced and can't be downgraded to standard", name)
+		}
+		v.Tier = types.ParameterTierAdvanced
+	case v.Tier == types.ParameterTierIntelligentTiering && len(v.Value) > maxStandardValue:
+		v.Tier = types.ParameterTierAdvanced
+	case v.Tier == types.ParameterTierIntelligentTiering && v.Policies != "":
+		v.Tier = types.ParameterTierAdvanced
+	case v.Tier == "" || v.Tier == types.ParameterTierIntelligentTiering:
+		v.Tier = types.ParameterTierStandard
+	}
+	if v.Policies != "" {
+		if v.Tier != types.ParameterTierAdvanced {
+			return nil, validationError("parameter policies are only supported for the advanced tier")
+		}
+		if err := checkPolicies(v.Policies); err != nil {
+			return nil, err
+		}
+	}
+	limit := maxStandardValue
+	if v.Tier == types.ParameterTierAdvanced {
+		limit = maxAdvancedValue
+	}
+	if len(v.Value) < 1 || len(v.Value) > limit {
+		return nil, validationError("value of %s must be between 1 and %d bytes for the %s tier", name, limit, v.Tier)
+	}
+
+	v = c.put(name, v)
+	for _, tag := range params.Tags {
+		c.parameters[name].tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return &ssm.PutParameterOutput{Version: v.Version, Tier: v.Tier}, nil
+}
+
+func (c *Client) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetParametersByPath"); err != nil {
+		return nil, err
+	}
+
+	path := aws.ToString(params.Path)
+	if !strings.HasPrefix(path, "/") {
+		return nil, validationError("path must start with /")
+	}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+
+	// Only Label filters are supported here, as in SSM, which rejects tag
+	// filters on this call.
+	var labels []string
+	for _, f := range params.ParameterFilters {
+		if aws.ToString(f.Key) != "Label" {
+			return nil, &types.InvalidFilterKey{Message: aws.String(fmt.Sprintf("filter key %s is not supported by GetParametersByPath", aws.ToString(f.Key)))}
+		}
+		labels = append(labels, f.Values...)
+	}
+
+	var matches []string
+	for _, name := range c.sortedNames() {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if !aws.ToBool(params.Recursive) && strings.Contains(rest, "/") {
+			continue
+		}
+		if len(labels) > 0 && !c.hasLabel(name, labels) {
+			continue
+		}
+		matches = append(matches, name)
+	}
+
+	start, end, next, err := page(len(matches), params.NextToken, params.MaxResults, maxPathResults)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ssm.GetParametersByPathOutput{NextToken: next}
+	for _, name := range matches[start:end] {
+		out.Parameters = append(out.Parameters, toParameter(name, c.parameters[name].latest()))
+	}
+	return out, nil
+}
+
+func (c *Client) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetParameters"); err != nil {
+		return nil, err
+	}
+
+	if len(params.Names) < 1 || len(params.Names) > maxBatchNames {
+		return nil, validationError("names must hold between 1 and %d names", maxBatchNames)
+	}
+
+	out := &ssm.GetParametersOutput{}
+	for _, requested := range params.Names {
+		name, selector, _ := strings.Cut(requested, ":")
+		v, ok := c.selectVersion(name, selector)
+		if !ok {
+			out.InvalidParameters = append(out.InvalidParameters, requested)
+			continue
+		}
+		param := toParameter(name, v)
+		if selector != "" {
+			param.Selector = aws.String(":" + selector)
+		}
+		out.Parameters = append(out.Parameters, param)
+	}
+	return out, nil
+}
+
+// selectVersion returns the version of name a selector picks: a version
+// number, a label, or the latest version when the selector is empty.
+func (c *Client) selectVersion(name, selector string) (Version, bool) {
+	p, ok := c.parameters[name]
+	if !ok {
+		return Version{}, false
+	}
+	if selector == "" {
+		return p.latest(), true
+	}
+
+	n, err := strconv.ParseInt(selector, 10, 64)
+	for _, v := range p.versions {
+		if err == nil && v.Version == n {
+			return v, true
+		}
+		for _, label := range v.Labels {
+			if label == selector {
+				return v, true
+			}
+		}
+	}
+	return Version{}, false
+}
+
+func (c *Client) hasLabel(name string, labels []string) bool {
+	for _, want := range labels {
+		if _, ok := c.selectVersion(name, want); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) DeleteParameters(ctx context.Context, params *ssm.DeleteParametersInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParametersOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DeleteParameters"); err != nil {
+		return nil, err
+	}
+
+	if len(params.Names) < 1 || len(params.Names) > maxBatchNames {
+		return nil, validationError("names must hold between 1 and %d names", maxBatchNames)
+	}
+
+	out := &ssm.DeleteParametersOutput{}
+	for _, name := range params.Names {
+		if _, ok := c.parameters[name]; !ok {
+			out.InvalidParameters = append(out.InvalidParameters, name)
+			continue
+		}
+		delete(c.parameters, name)
+		out.DeletedParameters = append(out.DeletedParameters, name)
+	}
+	return out, nil
+}
+
+func (c *Client) GetParameterHistory(ctx context.Context, params *ssm.GetParameterHistoryInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterHistoryOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetParameterHistory"); err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.Name)
+	p, ok := c.parameters[name]
+	if !ok {
+		return nil, &types.ParameterNotFound{Message: aws.String(fmt.Sprintf("Parameter %s not found.", name))}
+	}
+
+	start, end, next, err := page(len(p.versions), params.NextToken, params.MaxResults, maxHistoryResults)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ssm.GetParameterHistoryOutput{NextToken: next}
+	for _, v := range p.versions[start:end] {
+		out.Parameters = append(out.Parameters, types.ParameterHistory{
+			Name:             aws.String(name),
+			Value:            aws.String(v.Value),
+			Type:             v.Type,
+			KeyId:            aws.String(v.KeyID),
+			Version:          v.Version,
+			Labels:           v.Labels,
+			LastModifiedDate: aws.Time(v.LastModified),
+			LastModifiedUser: aws.String(User),
+			Tier:             v.Tier,
+		})
+	}
+	return out, nil
+}
+
+func (c *Client) AddTagsToResource(ctx context.Context, params *ssm.AddTagsToResourceInput, optFns ...func(*ssm.Options)) (*ssm.AddTagsToResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "AddTagsToResource"); err != nil {
+		return nil, err
+	}
+
+	if params.ResourceType != types.ResourceTypeForTaggingParameter {
+		return nil, &types.InvalidResourceType{Message: aws.String("only Parameter resources are supported")}
+	}
+	if len(params.Tags) == 0 {
+		return nil, validationError("at least one tag is required")
+	}
+
+	name := aws.ToString(params.ResourceId)
+	p, ok := c.parameters[name]
+	if !ok {
+		return nil, &types.InvalidResourceId{Message: aws.String(fmt.Sprintf("Parameter %s not found.", name))}
+	}
+	for _, tag := range params.Tags {
+		p.tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+// DescribeParameters supports the Path filter, with the Recursive and
+// OneLevel options, and tag:<key> filters with the Equals option.
+func (c *Client) DescribeParameters(ctx context.Context, params *ssm.DescribeParametersInput, optFns ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DescribeParameters"); err != nil {
+		return nil, err
+	}
+
+	for _, f := range params.ParameterFilters {
+		key, option := aws.ToString(f.Key), aws.ToString(f.Option)
+		switch {
+		case key == "Path":
+			if option != "" && option != "Recursive" && option != "OneLevel" {
+				return nil, &types.InvalidFilterOption{Message: aws.String(fmt.Sprintf("invalid option %s for Path", option))}
+			}
+		case strings.HasPrefix(key, "tag:"):
+			if option != "" && option != "Equals" {
+				return nil, &types.InvalidFilterOption{Message: aws.String(fmt.Sprintf("invalid option %s for %s", option, key))}
+			}
+		default:
+			return nil, &types.InvalidFilterKey{Message: aws.String(fmt.Sprintf("filter key %s is not supported", key))}
+		}
+		if len(f.Values) == 0 {
+			return nil, &types.InvalidFilterValue{Message: aws.String(fmt.Sprintf("filter %s needs a value", key))}
+		}
+	}
+
+	var matches []string
+	for _, name := range c.sortedNames() {
+		if c.matchesFilters(name, params.ParameterFilters) {
+			matches = append(matches, name)
+		}
+	}
+
+	start, end, next, err := page(len(matches), params.NextToken, params.MaxResults, maxDescribeResults)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ssm.DescribeParametersOutput{NextToken: next}
+	for _, name := range matches[start:end] {
+		v := c.parameters[name].latest()
+		out.Parameters = append(out.Parameters, types.ParameterMetadata{
+			Name:             aws.String(name),
+			Type:             v.Type,
+			KeyId:            aws.String(v.KeyID),
+			Version:          v.Version,
+			LastModifiedDate: aws.Time(v.LastModified),
+			LastModifiedUser: aws.String(User),
+			Tier:             v.Tier,
+			DataType:         aws.String("text"),
+			Policies:         inlinePolicies(v.Policies),
+		})
+	}
+	return out, nil
+}
+
+// matchesFilters reports whether name passes every filter. A filter with
+// several values matches any of them.
+func (c *Client) matchesFilters(name string, filters []types.ParameterStringFilter) bool {
+	for _, f := range filters {
+		key := aws.ToString(f.Key)
+		matched := false
+		for _, value := range f.Values {
+			if key == "Path" {
+				rest, ok := strings.CutPrefix(name, strings.TrimSuffix(value, "/")+"/")
+				matched = ok && (aws.ToString(f.Option) == "Recursive" || !strings.Contains(rest, "/"))
+			} else {
+				tag, ok := c.parameters[name].tags[strings.TrimPrefix(key, "tag:")]
+				matched = ok && tag == value
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// LabelParameterVersion moves each label to the given version, or the latest
+// version when none is given. Labels SSM would reject are returned as invalid.
+func (c *Client) LabelParameterVersion(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "LabelParameterVersion"); err != nil {
+		return nil, err
+	}
+
+	if len(params.Labels) < 1 || len(params.Labels) > maxVersionLabels {
+		return nil, validationError("labels must hold between 1 and %d labels", maxVersionLabels)
+	}
+
+	name := aws.ToString(params.Name)
+	p, ok := c.parameters[name]
+	if !ok {
+		return nil, &types.ParameterNotFound{Message: aws.String(fmt.Sprintf("Parameter %s not found.", name))}
+	}
+
+	target := len(p.versions) - 1
+	if params.ParameterVersion != nil {
+		target = int(*params.ParameterVersion) - 1
+		if target < 0 || target >= len(p.versions) {
+			return nil, &types.ParameterVersionNotFound{Message: aws.String(fmt.Sprintf("Version %d of %s not found.", *params.ParameterVersion, name))}
+		}
+	}
+
+	out := &ssm.LabelParameterVersionOutput{ParameterVersion: p.versions[target].Version}
+	var valid []string
+	for _, label := range params.Labels {
+		if !validLabel(label) {
+			out.InvalidLabels = append(out.InvalidLabels, label)
+			continue
+		}
+		valid = append(valid, label)
+	}
+
+	kept := 0
+	for _, label := range p.versions[target].Labels {
+		if !contains(valid, label) {
+			kept++
+		}
+	}
+	if kept+len(valid) > maxVersionLabels {
+		return nil, &types.ParameterVersionLabelLimitExceeded{Message: aws.String(fmt.Sprintf("A version can have at most %d labels.", maxVersionLabels))}
+	}
+
+	// A label names a single version, so it moves off any other version.
+	for i := range p.versions {
+		kept := p.versions[i].Labels[:0:0]
+		for _, label := range p.versions[i].Labels {
+			if !contains(valid, label) {
+				kept = append(kept, label)
+			}
+		}
+		p.versions[i].Labels = kept
+	}
+	p.versions[target].Labels = append(p.versions[target].Labels, valid...)
+	return out, nil
+}
+
+// validLabel applies the SSM rules: at most 100 characters, letters, digits,
+// periods, hyphens and underscores, not starting with a digit or with aws or ssm.
+func validLabel(label string) bool {
+	if label == "" || len(label) > 100 || (label[0] >= '0' && label[0] <= '9') {
+		return false
+	}
+	lower := strings.ToLower(label)
+	if strings.HasPrefix(lower, "aws") || strings.HasPrefix(lower, "ssm") {
+		return false
+	}
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+type policy struct {
+	Type       string
+	Version    string
+	Attributes map[string]string
+}
+
+// checkPolicies rejects policy JSON SSM would not accept.
+func checkPolicies(text string) error {
+	var policies []policy
+	if err := json.Unmarshal([]byte(text), &policies); err != nil {
+		return &types.InvalidPolicyTypeException{Message: aws.String(fmt.Sprintf("policies are not valid JSON: %s", err))}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range policies {
+		if seen[p.Type] {
+			return &types.InvalidPolicyTypeException{Message: aws.String(fmt.Sprintf("policy %s is given more than once", p.Type))}
+		}
+		seen[p.Type] = true
+
+		var err error
+		switch p.Type {
+		case "Expiration":
+			_, err = time.Parse(time.RFC3339, p.Attributes["Timestamp"])
+		case "ExpirationNotification":
+			err = checkPolicyAmount(p.Attributes["Before"], p.Attributes["Unit"])
+		case "NoChangeNotification":
+			err = checkPolicyAmount(p.Attributes["After"], p.Attributes["Unit"])
+		default:
+			return &types.InvalidPolicyTypeException{Message: aws.String(fmt.Sprintf("unknown policy type %q", p.Type))}
+		}
+		if err != nil || p.Version != "1.0" {
+			return &types.InvalidPolicyAttributeException{Message: aws.String(fmt.Sprintf("invalid attributes for policy %s", p.Type))}
+		}
+	}
+	return nil
+}
+
+func checkPolicyAmount(amount, unit string) error {
+	if n, err := strconv.Atoi(amount); err != nil || n <= 0 {
+		return fmt.Errorf("invalid amount %q", amount)
+	}
+	if unit != "Days" && unit != "Hours" {
+		return fmt.Errorf("invalid unit %q", unit)
+	}
+	return nil
+}
+
+func inlinePolicies(text string) []types.ParameterInlinePolicy {
+	var policies []policy
+	if json.Unmarshal([]byte(text), &policies) != nil {
+		return nil
+	}
+
+	inline := make([]types.ParameterInlinePolicy, 0, len(policies))
+	for _, p := range policies {
+		inline = append(inline, types.ParameterInlinePolicy{
+			PolicyText:   aws.String(text),
+			PolicyType:   aws.String(p.Type),
+			PolicyStatus: aws.String("Pending"),
+		})
+	}
+	return inline
+}
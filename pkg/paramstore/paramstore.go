@@ -3,104 +3,713 @@ package paramstore
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
 )
 
+// SSMAPI is the part of the SSM client ParamStore uses. Tests swap in an
+// in-memory stand-in such as fakessm.Client.
+type SSMAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+	GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error)
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+	DeleteParameters(ctx context.Context, params *ssm.DeleteParametersInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParametersOutput, error)
+	GetParameterHistory(ctx context.Context, params *ssm.GetParameterHistoryInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterHistoryOutput, error)
+	DescribeParameters(ctx context.Context, params *ssm.DescribeParametersInput, optFns ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error)
+	AddTagsToResource(ctx context.Context, params *ssm.AddTagsToResourceInput, optFns ...func(*ssm.Options)) (*ssm.AddTagsToResourceOutput, error)
+	LabelParameterVersion(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error)
+}
+
 type ParamStore struct {
-	SSMClient *ssm.Client
-	SSMPath   string
+	SSMClient  SSMAPI
+	SSMPath    string
+	KeyMapping KeyMapping
+	// KMSKeyID encrypts SecureString parameters. Empty means aws/ssm.
+	KMSKeyID string
+	// Tier is standard, advanced or intelligent. Empty means standard.
+	Tier string
+	// Tags are put on every parameter written.
+	Tags map[string]string
+	// Policies are put on each parameter written, which makes it advanced.
+	Policies KeyPolicies
+	// Filter narrows what GetParameters returns.
+	Filter Filter
+	// Concurrency is how many puts PutParameters makes at once. Zero means
+	// DefaultConcurrency.
+	Concurrency int
+	// CallTimeout bounds every single SSM call. Zero means DefaultCallTimeout.
+	// The context passed to each method bounds the operation as a whole.
+	CallTimeout time.Duration
+
+	// pacer slows every call down while SSM throttles.
+	pacer *pacer
+	// mu guards types and versions, which concurrent puts update.
+	mu sync.Mutex
+
+	// names maps keys to the full parameter names they were read from, so a
+	// nested parameter is written back where it came from.
+	names map[string]string
+	// types holds the type of every parameter read so far.
+	types map[string]string
+	// versions holds the version of every parameter read so far.
+	versions map[string]int64
+}
+
+// KeyMapping turns a parameter name relative to the path into an env var
+// name. Separator replaces the slashes of nested names, and the dots and
+// dashes parameter names may hold but env var names may not. It defaults to
+// "_". Case is upper, lower or preserve.
+type KeyMapping struct {
+	Separator string
+	Case      string
 }
 
-func NewParamStore(ssmPath string) (*ParamStore, error) {
+func (m KeyMapping) Key(relative string) string {
+	sep := m.Separator
+	if sep == "" {
+		sep = "_"
+	}
+
+	key := strings.NewReplacer("/", sep, ".", sep, "-", sep).Replace(relative)
+	switch m.Case {
+	case "upper":
+		return strings.ToUpper(key)
+	case "lower":
+		return strings.ToLower(key)
+	default:
+		return key
+	}
+}
+
+// DefaultCallTimeout bounds a single SSM call when CallTimeout is not set.
+const DefaultCallTimeout = 5 * time.Second
+
+// EndpointEnvVar names the environment variable that points ime at another
+// SSM endpoint, such as LocalStack. It wins over AWSOptions.EndpointURL.
+const EndpointEnvVar = "IME_SSM_ENDPOINT"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func NewParamStore(ctx context.Context, ssmPath string, awsOpts AWSOptions) (*ParamStore, error) {
+	if endpoint := os.Getenv(EndpointEnvVar); endpoint != "" {
+		awsOpts.EndpointURL = endpoint
+	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
+	cfg, err := loadAWSConfig(ctx, awsOpts)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load AWS SDK config, %v", err)
+		return nil, err
 	}
 
+	return NewParamStoreWithClient(newSSMClient(cfg, awsOpts.EndpointURL), ssmPath), nil
+}
+
+func newSSMClient(cfg aws.Config, endpointURL string) *ssm.Client {
+	return ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+		if endpointURL != "" {
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+	})
+}
+
+// NewParamStoreWithClient returns a ParamStore that talks to client instead of
+// building an SSM client from the AWS configuration.
+func NewParamStoreWithClient(client SSMAPI, ssmPath string) *ParamStore {
 	return &ParamStore{
-		SSMClient: ssm.NewFromConfig(cfg),
+		SSMClient: client,
 		SSMPath:   ssmPath,
-	}, nil
+		names:     make(map[string]string),
+		types:     make(map[string]string),
+		versions:  make(map[string]int64),
+		pacer:     &pacer{},
+	}
+}
+
+var _ Backend = (*ParamStore)(nil)
+
+func (p *ParamStore) Path() string {
+	return p.SSMPath
 }
 
 func (p *ParamStore) FormatParamName(name string) string {
+	if full, ok := p.names[name]; ok {
+		return full
+	}
 	return fmt.Sprintf("%s/%s", p.SSMPath, name)
 }
 
-func (p *ParamStore) BuildPutParamInput(name, value string, overwrite bool) *ssm.PutParameterInput {
-	return &ssm.PutParameterInput{
-		Name:      aws.String(p.FormatParamName(name)),
-		Value:     aws.String(value),
-		Type:      types.ParameterTypeSecureString,
+func (p *ParamStore) BuildPutParamInput(param Parameter, overwrite bool) *ssm.PutParameterInput {
+	paramType := types.ParameterType(param.Type)
+	if paramType == "" {
+		paramType = types.ParameterTypeSecureString
+	}
+
+	input := &ssm.PutParameterInput{
+		Name:      aws.String(p.FormatParamName(param.Key)),
+		Value:     aws.String(param.Value),
+		Type:      paramType,
 		Overwrite: aws.Bool(overwrite),
+		Tier:      sdkTier(p.Tier),
+	}
+	if paramType == types.ParameterTypeSecureString && p.KMSKeyID != "" {
+		input.KeyId = aws.String(p.KMSKeyID)
+	}
+	if policies := p.Policies.For(param.Key); !policies.IsZero() {
+		input.Policies = aws.String(policyJSON(policies, time.Now()))
+		input.Tier = types.ParameterTierAdvanced
+	}
+	// SSM refuses tags on an overwrite, PutParameter tags those separately.
+	if !overwrite {
+		input.Tags = p.buildTags()
+	}
+	return input
+}
+
+// buildTags returns Tags sorted by key, or nil when there are none.
+func (p *ParamStore) buildTags() []types.Tag {
+	keys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tags []types.Tag
+	for _, k := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(p.Tags[k])})
 	}
+	return tags
 }
 
 func (p *ParamStore) BuildGetParamsByPathInput(next string) *ssm.GetParametersByPathInput {
-	return &ssm.GetParametersByPathInput{
+	input := &ssm.GetParametersByPathInput{
 		Path:           aws.String(p.SSMPath),
 		WithDecryption: aws.Bool(true),
-		NextToken:      aws.String(next),
+		Recursive:      aws.Bool(true),
 		MaxResults:     aws.Int32(10),
 	}
+	if p.Filter.Label != "" {
+		input.ParameterFilters = []types.ParameterStringFilter{{
+			Key:    aws.String("Label"),
+			Option: aws.String("Equals"),
+			Values: []string{p.Filter.Label},
+		}}
+	}
+	if next != "" {
+		input.NextToken = aws.String(next)
+	}
+	return input
 }
 
-func (p *ParamStore) PutParameters(params map[string]string, overwrite bool) error {
+func (p *ParamStore) PutParameter(ctx context.Context, param Parameter, overwrite bool) (int64, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	input := p.BuildPutParamInput(param, overwrite)
 
-	for k, v := range params {
-		params := p.BuildPutParamInput(k, v, overwrite)
-		r, err := p.SSMClient.PutParameter(ctx, params)
+	var version int64
+	err := p.call(ctx, func(ctx context.Context) error {
+		r, err := p.SSMClient.PutParameter(ctx, input)
 		if err != nil {
-			return fmt.Errorf("Error putting parameter %s: %s", k, err)
+			return err
 		}
-		fmt.Printf("Parameter added: %s Version: %d\n", *params.Name, r.Version)
+		version = r.Version
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Error putting parameter %s: %w", param.Key, err)
 	}
-	return nil
+
+	p.mu.Lock()
+	p.types[param.Key] = string(input.Type)
+	p.versions[param.Key] = version
+	p.mu.Unlock()
+
+	if overwrite && len(p.Tags) > 0 {
+		err := p.call(ctx, func(ctx context.Context) error {
+			_, err := p.SSMClient.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+				ResourceType: types.ResourceTypeForTaggingParameter,
+				ResourceId:   input.Name,
+				Tags:         p.buildTags(),
+			})
+			return err
+		})
+		if err != nil {
+			return version, fmt.Errorf("Error tagging parameter %s: %w", param.Key, err)
+		}
+	}
+	return version, nil
 }
 
-func (p *ParamStore) GetParameters() (map[string]string, error) {
+// PutParameters writes params with up to Concurrency puts in flight, backing
+// off while SSM throttles. It returns the version written for every key that
+// succeeded, and a *PutError holding the reason for every key that did not.
+// Keys not yet started when ctx is done fail with the context's error.
+func (p *ParamStore) PutParameters(ctx context.Context, params []Parameter, overwrite bool) (map[string]int64, error) {
+	workers := p.Concurrency
+	if workers < 1 {
+		workers = DefaultConcurrency
+	}
+	if workers > len(params) {
+		workers = len(params)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	var mu sync.Mutex
+	versions := make(map[string]int64)
+	failed := make(map[string]error)
+
+	jobs := make(chan Parameter)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for param := range jobs {
+				version, err := p.PutParameter(ctx, param, overwrite)
+
+				mu.Lock()
+				if err != nil {
+					failed[param.Key] = err
+				} else {
+					versions[param.Key] = version
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, param := range params {
+		jobs <- param
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return versions, &PutError{Failed: failed}
+	}
+	return versions, nil
+}
+
+// DeleteParameters removes the given keys in batches of 10, the most SSM accepts
+// in a single call. It returns the keys that were deleted and, for every key that
+// was not, the reason why.
+func (p *ParamStore) DeleteParameters(ctx context.Context, keys []string) ([]string, map[string]error) {
+
+	var deleted []string
+	failed := make(map[string]error)
+
+	for start := 0; start < len(keys); start += 10 {
+		end := start + 10
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		names := make([]string, len(batch))
+		for i, k := range batch {
+			names[i] = p.FormatParamName(k)
+		}
+
+		var result *ssm.DeleteParametersOutput
+		err := p.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = p.SSMClient.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: names})
+			return err
+		})
+
+		if err != nil {
+			for _, k := range batch {
+				failed[k] = fmt.Errorf("Error deleting parameter %s: %w", k, err)
+			}
+			continue
+		}
+
+		for _, name := range result.DeletedParameters {
+			deleted = append(deleted, p.ParseParameterName(name))
+		}
+		for _, name := range result.InvalidParameters {
+			k := p.ParseParameterName(name)
+			failed[k] = fmt.Errorf("parameter %s was not deleted: invalid or not found", name)
+		}
+	}
+	return deleted, failed
+}
+
+// GetParameters returns every parameter under the path, narrowed by Filter.
+// With a label the labeled versions are returned, selected as name:label,
+// and a label no parameter carries is an error.
+func (p *ParamStore) GetParameters(ctx context.Context) (map[string]string, error) {
+
+	var found []types.Parameter
+	var err error
+	if len(p.Filter.Tags) > 0 {
+		found, err = p.getTaggedParameters(ctx)
+	} else {
+		found, err = p.getParametersByPath(ctx)
+		if err == nil && p.Filter.Label != "" {
+			names := make([]string, len(found))
+			for i, param := range found {
+				names[i] = *param.Name
+			}
+			found, err = p.getParametersByFullName(ctx, names)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 && p.Filter.Label != "" {
+		return nil, fmt.Errorf("no parameters under %s carry label %s", p.SSMPath, p.Filter.Label)
+	}
 
-	next := ""
 	params := make(map[string]string)
+	names := make(map[string]string)
+	var collisions []string
 
+	for _, param := range found {
+		n := p.ParseParameterName(*param.Name)
+		if other, seen := names[n]; seen {
+			collisions = append(collisions, fmt.Sprintf("%s and %s both map to %s", other, *param.Name, n))
+			continue
+		}
+		names[n] = *param.Name
+		params[n] = *param.Value
+		p.types[n] = string(param.Type)
+		p.versions[n] = param.Version
+	}
+
+	if len(collisions) > 0 {
+		return nil, fmt.Errorf("parameter names collide under %s: %s", p.SSMPath, strings.Join(collisions, "; "))
+	}
+
+	p.names = names
+	return params, nil
+}
+
+func (p *ParamStore) getParametersByPath(ctx context.Context) ([]types.Parameter, error) {
+	var found []types.Parameter
+	next := ""
 	for {
 		input := p.BuildGetParamsByPathInput(next)
-		result, err := p.SSMClient.GetParametersByPath(ctx, input)
+		var result *ssm.GetParametersByPathOutput
+		err := p.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = p.SSMClient.GetParametersByPath(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error getting parameters: %w", err)
+		}
+		found = append(found, result.Parameters...)
+
+		if result.NextToken == nil {
+			return found, nil
+		}
+		next = *result.NextToken
+	}
+}
 
+// BuildDescribeParamsInput selects the parameters under the path that carry
+// every tag of the filter. GetParametersByPath does not accept tag filters, so
+// tagged parameters are found with DescribeParameters instead.
+func (p *ParamStore) BuildDescribeParamsInput(next string) *ssm.DescribeParametersInput {
+	return p.buildDescribeParamsInput(p.Filter.Tags, next)
+}
+
+func (p *ParamStore) buildDescribeParamsInput(tags map[string]string, next string) *ssm.DescribeParametersInput {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	input := &ssm.DescribeParametersInput{
+		ParameterFilters: []types.ParameterStringFilter{{
+			Key:    aws.String("Path"),
+			Option: aws.String("Recursive"),
+			Values: []string{p.SSMPath},
+		}},
+		MaxResults: aws.Int32(50),
+	}
+	for _, k := range keys {
+		input.ParameterFilters = append(input.ParameterFilters, types.ParameterStringFilter{
+			Key:    aws.String("tag:" + k),
+			Option: aws.String("Equals"),
+			Values: []string{tags[k]},
+		})
+	}
+	if next != "" {
+		input.NextToken = aws.String(next)
+	}
+	return input
+}
+
+func (p *ParamStore) getTaggedParameters(ctx context.Context) ([]types.Parameter, error) {
+	names, err := p.describeNames(ctx, p.Filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return p.getParametersByFullName(ctx, names)
+}
+
+// describeNames returns the full names of the parameters under the path that
+// carry every one of tags.
+func (p *ParamStore) describeNames(ctx context.Context, tags map[string]string) ([]string, error) {
+	var names []string
+	next := ""
+	for {
+		var result *ssm.DescribeParametersOutput
+		err := p.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = p.SSMClient.DescribeParameters(ctx, p.buildDescribeParamsInput(tags, next))
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("Error getting parameters: %s", err)
+			return nil, fmt.Errorf("Error describing parameters: %w", err)
+		}
+		for _, meta := range result.Parameters {
+			names = append(names, *meta.Name)
+		}
+
+		if result.NextToken == nil {
+			return names, nil
+		}
+		next = *result.NextToken
+	}
+}
+
+// getParametersByFullName gets the named parameters in batches of 10, the
+// most SSM accepts in a single call. With a label Filter the labeled version
+// of each is selected, and a name without one is left out.
+func (p *ParamStore) getParametersByFullName(ctx context.Context, names []string) ([]types.Parameter, error) {
+	var found []types.Parameter
+	for start := 0; start < len(names); start += 10 {
+		end := start + 10
+		if end > len(names) {
+			end = len(names)
+		}
+
+		batch := names[start:end]
+		if p.Filter.Label != "" {
+			batch = make([]string, end-start)
+			for i, name := range names[start:end] {
+				batch[i] = name + ":" + p.Filter.Label
+			}
+		}
+
+		var result *ssm.GetParametersOutput
+		err := p.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = p.SSMClient.GetParameters(ctx, &ssm.GetParametersInput{
+				Names:          batch,
+				WithDecryption: aws.Bool(true),
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error getting parameters: %w", err)
+		}
+		found = append(found, result.Parameters...)
+	}
+	return found, nil
+}
+
+// GetParametersByName fetches the given keys in batches of 10, the most SSM
+// accepts in a single call. With a label Filter the labeled version of each
+// key is fetched, and a key without one is missing. A key with no parameter
+// of its own name may be one the key mapping made of a nested name, such as
+// DB_HOST for db/host, so the names under the path are listed to find those.
+func (p *ParamStore) GetParametersByName(ctx context.Context, keys []string) (map[string]string, []string, error) {
+	params, missing, err := p.getParametersByKey(ctx, keys)
+	if err != nil || len(missing) == 0 {
+		return params, missing, err
+	}
+
+	if err := p.resolveNames(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	var nested, notFound []string
+	for _, k := range missing {
+		if name, ok := p.names[k]; ok && name != fmt.Sprintf("%s/%s", p.SSMPath, k) {
+			nested = append(nested, k)
+		} else {
+			notFound = append(notFound, k)
+		}
+	}
+	if len(nested) == 0 {
+		return params, missing, nil
+	}
+
+	found, stillMissing, err := p.getParametersByKey(ctx, nested)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range found {
+		params[k] = v
+	}
+	return params, append(notFound, stillMissing...), nil
+}
+
+// getParametersByKey fetches keys by the names FormatParamName gives them.
+func (p *ParamStore) getParametersByKey(ctx context.Context, keys []string) (map[string]string, []string, error) {
+
+	params := make(map[string]string)
+	var missing []string
+
+	for start := 0; start < len(keys); start += 10 {
+		end := start + 10
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		names := make([]string, 0, end-start)
+		requested := make(map[string]string, end-start)
+		for _, k := range keys[start:end] {
+			name := p.FormatParamName(k)
+			requested[name] = k
+			if p.Filter.Label != "" {
+				name += ":" + p.Filter.Label
+			}
+			names = append(names, name)
+		}
+
+		var result *ssm.GetParametersOutput
+		err := p.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = p.SSMClient.GetParameters(ctx, &ssm.GetParametersInput{
+				Names:          names,
+				WithDecryption: aws.Bool(true),
+			})
+			return err
+		})
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error getting parameters: %w", err)
 		}
 
 		for _, param := range result.Parameters {
-			n := p.ParseParameterName(*param.Name)
+			n, ok := requested[*param.Name]
+			if !ok {
+				n = p.ParseParameterName(*param.Name)
+			}
 			params[n] = *param.Value
+			p.types[n] = string(param.Type)
+			p.versions[n] = param.Version
+		}
+		for _, name := range result.InvalidParameters {
+			if p.Filter.Label != "" {
+				name = strings.TrimSuffix(name, ":"+p.Filter.Label)
+			}
+			n, ok := requested[name]
+			if !ok {
+				n = p.ParseParameterName(name)
+			}
+			missing = append(missing, n)
+		}
+	}
+	return params, missing, nil
+}
+
+// resolveNames records the full name of every parameter under the path by
+// the key it maps to, so FormatParamName finds nested names.
+func (p *ParamStore) resolveNames(ctx context.Context) error {
+	found, err := p.describeNames(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]string, len(found))
+	var collisions []string
+	for _, name := range found {
+		n := p.ParseParameterName(name)
+		if other, seen := names[n]; seen {
+			collisions = append(collisions, fmt.Sprintf("%s and %s both map to %s", other, name, n))
+			continue
+		}
+		names[n] = name
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("parameter names collide under %s: %s", p.SSMPath, strings.Join(collisions, "; "))
+	}
+
+	p.names = names
+	return nil
+}
+
+func (p *ParamStore) ParameterTypes() map[string]string {
+	return p.types
+}
+
+func (p *ParamStore) LabelParameter(ctx context.Context, key, label string) error {
+
+	input := &ssm.LabelParameterVersionInput{
+		Name:   aws.String(p.FormatParamName(key)),
+		Labels: []string{label},
+	}
+	if version, ok := p.versions[key]; ok {
+		input.ParameterVersion = aws.Int64(version)
+	}
+
+	var result *ssm.LabelParameterVersionOutput
+	err := p.call(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = p.SSMClient.LabelParameterVersion(ctx, input)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error labeling parameter %s: %w", key, err)
+	}
+	if len(result.InvalidLabels) > 0 {
+		return fmt.Errorf("label %s is not valid for parameter %s", strings.Join(result.InvalidLabels, ", "), key)
+	}
+	return nil
+}
+
+func (p *ParamStore) GetParameterHistory(ctx context.Context, key string) ([]ParameterVersion, error) {
+
+	var versions []ParameterVersion
+	input := &ssm.GetParameterHistoryInput{
+		Name:           aws.String(p.FormatParamName(key)),
+		WithDecryption: aws.Bool(true),
+	}
+
+	for {
+		var result *ssm.GetParameterHistoryOutput
+		err := p.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = p.SSMClient.GetParameterHistory(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error getting parameter history for %s: %w", key, err)
+		}
+
+		for _, h := range result.Parameters {
+			versions = append(versions, ParameterVersion{
+				Version:      h.Version,
+				Value:        aws.ToString(h.Value),
+				Type:         string(h.Type),
+				LastModified: aws.ToTime(h.LastModifiedDate),
+				ModifiedBy:   aws.ToString(h.LastModifiedUser),
+				Labels:       h.Labels,
+			})
 		}
 
 		if result.NextToken == nil {
 			break
 		}
-		next = *result.NextToken
+		input.NextToken = result.NextToken
 	}
-	return params, nil
+	return versions, nil
 }
 
+// ParseParameterName returns the key for a full parameter name, mapping any
+// levels nested below the path with KeyMapping.
 func (p *ParamStore) ParseParameterName(name string) string {
+	if relative, ok := strings.CutPrefix(name, p.SSMPath+"/"); ok {
+		return p.KeyMapping.Key(relative)
+	}
 	parts := strings.Split(name, "/")
 	return parts[len(parts)-1]
 }
@@ -0,0 +1,688 @@
+package paramstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/pytoolbelt/ime/pkg/paramstore/fakessm"
+)
+
+const testPath = "/global/project1/dev"
+
+func newTestParamStore() (*ParamStore, *fakessm.Client) {
+	client := fakessm.New()
+	return NewParamStoreWithClient(client, testPath), client
+}
+
+func TestPutParameter(t *testing.T) {
+	ps, client := newTestParamStore()
+
+	version, err := ps.PutParameter(context.Background(), Parameter{Key: "KEY", Value: "one"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error creating parameter: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("expected version 1, but got %d", version)
+	}
+
+	_, err = ps.PutParameter(context.Background(), Parameter{Key: "KEY", Value: "two"}, false)
+	var exists *types.ParameterAlreadyExists
+	if !errors.As(err, &exists) {
+		t.Errorf("expected ParameterAlreadyExists without overwrite, but got %v", err)
+	}
+
+	version, err = ps.PutParameter(context.Background(), Parameter{Key: "KEY", Value: "two"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error overwriting parameter: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("expected version 2, but got %d", version)
+	}
+
+	versions := client.Versions(testPath + "/KEY")
+	if len(versions) != 2 || versions[1].Value != "two" {
+		t.Errorf("expected two versions ending in 'two', but got %+v", versions)
+	}
+	if versions[1].Type != types.ParameterTypeSecureString {
+		t.Errorf("expected SecureString, but got %s", versions[1].Type)
+	}
+}
+
+func TestPutParameterKMSKey(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.KMSKeyID = "alias/prod"
+
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "SECRET", Value: "x"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "URL", Value: "y", Type: "String"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+
+	if v := client.Versions(testPath + "/SECRET"); v[0].KeyID != "alias/prod" {
+		t.Errorf("expected SECRET to use alias/prod, but got %s", v[0].KeyID)
+	}
+	if v := client.Versions(testPath + "/URL"); v[0].KeyID != "" {
+		t.Errorf("expected no key for a String parameter, but got %s", v[0].KeyID)
+	}
+}
+
+func TestPutParameterTags(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.Tags = map[string]string{"managed-by": "ime", "team": "payments"}
+
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "KEY", Value: "one"}, false); err != nil {
+		t.Fatalf("unexpected error creating parameter: %v", err)
+	}
+	if tags := client.Tags(testPath + "/KEY"); !reflect.DeepEqual(tags, ps.Tags) {
+		t.Errorf("expected tags %v on create, but got %v", ps.Tags, tags)
+	}
+
+	// SSM refuses tags together with overwrite, they are added separately.
+	client.Seed(map[string]string{testPath + "/MANUAL": "x"})
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "MANUAL", Value: "y"}, true); err != nil {
+		t.Fatalf("unexpected error overwriting parameter: %v", err)
+	}
+	if tags := client.Tags(testPath + "/MANUAL"); !reflect.DeepEqual(tags, ps.Tags) {
+		t.Errorf("expected tags %v on overwrite, but got %v", ps.Tags, tags)
+	}
+	if client.Calls["AddTagsToResource"] != 1 {
+		t.Errorf("expected 1 AddTagsToResource call, but got %d", client.Calls["AddTagsToResource"])
+	}
+}
+
+func TestGetParametersTagFilter(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.Tags = map[string]string{"managed-by": "ime"}
+
+	for i := 0; i < 12; i++ {
+		if _, err := ps.PutParameter(context.Background(), Parameter{Key: fmt.Sprintf("IME_%02d", i), Value: "x"}, false); err != nil {
+			t.Fatalf("unexpected error creating parameter: %v", err)
+		}
+	}
+	client.Seed(map[string]string{
+		testPath + "/MANUAL":      "hand made",
+		"/global/project1/prod/X": "other path",
+	})
+
+	ps.Filter = Filter{Tags: map[string]string{"managed-by": "ime"}}
+	params, err := ps.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+	if len(params) != 12 {
+		t.Errorf("expected the 12 tagged parameters, but got %d: %v", len(params), params)
+	}
+	if _, ok := params["MANUAL"]; ok {
+		t.Errorf("expected untagged MANUAL to be filtered out")
+	}
+	if client.Calls["GetParametersByPath"] != 0 {
+		t.Errorf("expected no GetParametersByPath calls with a tag filter, but got %d", client.Calls["GetParametersByPath"])
+	}
+
+	ps.Filter = Filter{Tags: map[string]string{"managed-by": "someone-else"}}
+	params, err = ps.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+	if len(params) != 0 {
+		t.Errorf("expected no parameters, but got %v", params)
+	}
+}
+
+func TestGetParametersLabelFilter(t *testing.T) {
+	ps, client := newTestParamStore()
+
+	for _, key := range []string{"A", "B"} {
+		if _, err := ps.PutParameter(context.Background(), Parameter{Key: key, Value: "v1"}, false); err != nil {
+			t.Fatalf("unexpected error creating parameter: %v", err)
+		}
+	}
+	if err := ps.LabelParameter(context.Background(), "A", "stable"); err != nil {
+		t.Fatalf("unexpected error labeling parameter: %v", err)
+	}
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "A", Value: "v2"}, true); err != nil {
+		t.Fatalf("unexpected error overwriting parameter: %v", err)
+	}
+
+	ps.Filter = Filter{Label: "stable"}
+	params, err := ps.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+	if len(params) != 1 || params["A"] != "v1" {
+		t.Errorf("expected only the labeled version A=v1, but got %v", params)
+	}
+	if versions := client.Versions(testPath + "/A"); len(versions[0].Labels) != 1 || len(versions[1].Labels) != 0 {
+		t.Errorf("expected the label on version 1 only, but got %+v", versions)
+	}
+
+	ps.Filter = Filter{Label: "unknown"}
+	if _, err := ps.GetParameters(context.Background()); err == nil {
+		t.Errorf("expected error for a label no parameter carries, but got none")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ps, _ := newTestParamStore()
+	deep := NewParamStoreWithClient(fakessm.New(), "/a/b/c/d/e/f/g/h/i/j/k/l/m/n/o")
+
+	tests := []struct {
+		ps         *ParamStore
+		tier       string
+		param      Parameter
+		violations int
+	}{
+		{ps, TierStandard, Parameter{Key: "OK", Value: "x"}, 0},
+		{ps, TierStandard, Parameter{Key: "EMPTY", Value: ""}, 1},
+		{ps, TierStandard, Parameter{Key: "BIG", Value: strings.Repeat("x", 4097)}, 1},
+		{ps, TierAdvanced, Parameter{Key: "BIG", Value: strings.Repeat("x", 4097)}, 0},
+		{ps, TierIntelligent, Parameter{Key: "HUGE", Value: strings.Repeat("x", 8193)}, 1},
+		{ps, TierStandard, Parameter{Key: strings.Repeat("K", 1000), Value: "x"}, 1},
+		{deep, TierStandard, Parameter{Key: "DEEP", Value: "x"}, 1},
+	}
+
+	for i, tt := range tests {
+		tt.ps.Tier = tt.tier
+		err := tt.ps.Validate([]Parameter{tt.param})
+
+		var invalid *ValidationError
+		switch {
+		case tt.violations == 0 && err != nil:
+			t.Errorf("case %d: unexpected error: %v", i, err)
+		case tt.violations > 0 && !errors.As(err, &invalid):
+			t.Errorf("case %d: expected a ValidationError, but got %v", i, err)
+		case tt.violations > 0 && len(invalid.Violations) != tt.violations:
+			t.Errorf("case %d: expected %d violations, but got %v", i, tt.violations, invalid.Violations)
+		}
+	}
+
+	err := ps.Validate([]Parameter{{Key: "A", Value: ""}, {Key: "B", Value: strings.Repeat("x", 9000)}})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Violations) != 2 {
+		t.Errorf("expected every violation to be reported together, but got %v", err)
+	}
+}
+
+func TestPutParameterTier(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.Tier = TierAdvanced
+
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "BIG", Value: strings.Repeat("x", 5000)}, false); err != nil {
+		t.Fatalf("unexpected error putting an advanced parameter: %v", err)
+	}
+	if versions := client.Versions(testPath + "/BIG"); versions[0].Tier != types.ParameterTierAdvanced {
+		t.Errorf("expected the advanced tier, but got %s", versions[0].Tier)
+	}
+}
+
+func TestPutParameterPolicies(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.Policies = KeyPolicies{
+		Default: Policies{NotifyIfUnchangedFor: 90 * 24 * time.Hour},
+		Keys: map[string]Policies{
+			"VENDOR_TOKEN": {ExpiresAfter: 7 * 24 * time.Hour, NotifyBeforeExpiry: 12 * time.Hour},
+		},
+	}
+
+	before := time.Now()
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "VENDOR_TOKEN", Value: "secret"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+
+	v := client.Versions(testPath + "/VENDOR_TOKEN")[0]
+	if v.Tier != types.ParameterTierAdvanced {
+		t.Errorf("expected parameters with policies to be advanced, but got %s", v.Tier)
+	}
+
+	var policies []policy
+	if err := json.Unmarshal([]byte(v.Policies), &policies); err != nil {
+		t.Fatalf("unexpected error reading policies %s: %v", v.Policies, err)
+	}
+	if len(policies) != 2 || policies[0].Type != "Expiration" || policies[1].Type != "ExpirationNotification" {
+		t.Fatalf("expected an expiration and a notification, but got %s", v.Policies)
+	}
+	expires, err := time.Parse(time.RFC3339, policies[0].Attributes["Timestamp"])
+	if err != nil || expires.Before(before.Add(7*24*time.Hour-time.Second)) || expires.After(time.Now().Add(7*24*time.Hour)) {
+		t.Errorf("expected the parameter to expire in 7 days, but got %s", policies[0].Attributes["Timestamp"])
+	}
+	if policies[1].Attributes["Before"] != "12" || policies[1].Attributes["Unit"] != "Hours" {
+		t.Errorf("expected a notification 12 hours before, but got %v", policies[1].Attributes)
+	}
+
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "FLAG", Value: "on"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	expected := `[{"Type":"NoChangeNotification","Version":"1.0","Attributes":{"After":"90","Unit":"Days"}}]`
+	if v := client.Versions(testPath + "/FLAG")[0]; v.Policies != expected {
+		t.Errorf("expected the default policies %s, but got %s", expected, v.Policies)
+	}
+}
+
+func TestValidatePolicies(t *testing.T) {
+	ps, _ := newTestParamStore()
+	ps.Policies = KeyPolicies{Keys: map[string]Policies{
+		"BIG":    {ExpiresAfter: 24 * time.Hour},
+		"NOTIFY": {NotifyBeforeExpiry: 24 * time.Hour},
+	}}
+
+	err := ps.Validate([]Parameter{
+		{Key: "BIG", Value: strings.Repeat("x", 5000)},
+		{Key: "NOTIFY", Value: "x"},
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, but got %v", err)
+	}
+	if len(verr.Violations) != 1 || !strings.HasPrefix(verr.Violations[0], "NOTIFY:") {
+		t.Errorf("expected only the notification without an expiration to be rejected, but got %v", verr.Violations)
+	}
+}
+
+func shortenBackoff(t *testing.T) {
+	base, max := throttleBaseDelay, throttleMaxDelay
+	throttleBaseDelay, throttleMaxDelay = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() {
+		throttleBaseDelay, throttleMaxDelay = base, max
+	})
+}
+
+func TestPutParametersThrottled(t *testing.T) {
+	shortenBackoff(t)
+	ps, client := newTestParamStore()
+	ps.Concurrency = 8
+
+	var params []Parameter
+	for i := 0; i < 150; i++ {
+		params = append(params, Parameter{Key: fmt.Sprintf("KEY_%03d", i), Value: "x"})
+	}
+	client.Throttle("PutParameter", 25)
+
+	versions, err := ps.PutParameters(context.Background(), params, false)
+	if err != nil {
+		t.Fatalf("unexpected error putting parameters: %v", err)
+	}
+	if len(versions) != 150 || len(client.Names()) != 150 {
+		t.Errorf("expected 150 parameters written, but got %d versions and %d names", len(versions), len(client.Names()))
+	}
+	if client.Calls["PutParameter"] != 175 {
+		t.Errorf("expected the 25 throttled puts to be retried, but got %d calls", client.Calls["PutParameter"])
+	}
+}
+
+func TestPutParametersReportsEveryFailure(t *testing.T) {
+	shortenBackoff(t)
+	ps, client := newTestParamStore()
+	client.Seed(map[string]string{testPath + "/EXISTS_A": "a", testPath + "/EXISTS_B": "b"})
+
+	params := []Parameter{{Key: "EXISTS_A", Value: "x"}, {Key: "NEW", Value: "x"}, {Key: "EXISTS_B", Value: "x"}}
+	versions, err := ps.PutParameters(context.Background(), params, false)
+
+	var putErr *PutError
+	if !errors.As(err, &putErr) {
+		t.Fatalf("expected a PutError, but got %v", err)
+	}
+	if len(putErr.Failed) != 2 || putErr.Failed["EXISTS_A"] == nil || putErr.Failed["EXISTS_B"] == nil {
+		t.Errorf("expected EXISTS_A and EXISTS_B to fail, but got %v", putErr.Failed)
+	}
+	if versions["NEW"] != 1 {
+		t.Errorf("expected NEW to be written, but got %v", versions)
+	}
+
+	// A throttle that never lets up fails the key once the attempts run out.
+	client.Throttle("PutParameter", throttleAttempts)
+	_, err = ps.PutParameters(context.Background(), []Parameter{{Key: "SLOW", Value: "x"}}, false)
+	if !errors.As(err, &putErr) || !isThrottle(putErr.Failed["SLOW"]) {
+		t.Errorf("expected SLOW to fail with a throttling error, but got %v", err)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	ps, client := newTestParamStore()
+	client.Seed(map[string]string{testPath + "/KEY": "x"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ps.GetParameters(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected GetParameters to stop with context.Canceled, but got %v", err)
+	}
+
+	params := []Parameter{{Key: "A", Value: "x"}, {Key: "B", Value: "x"}}
+	_, err := ps.PutParameters(ctx, params, false)
+	var putErr *PutError
+	if !errors.As(err, &putErr) || len(putErr.Failed) != 2 || !errors.Is(putErr.Failed["A"], context.Canceled) {
+		t.Errorf("expected every key to fail with context.Canceled, but got %v", err)
+	}
+	if client.Calls["PutParameter"] != 0 {
+		t.Errorf("expected no puts after cancel, but got %d", client.Calls["PutParameter"])
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	ps, _ := newTestParamStore()
+	ps.CallTimeout = time.Nanosecond
+
+	err := ps.call(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the call to hit its own deadline, but got %v", err)
+	}
+}
+
+func TestGetParametersPages(t *testing.T) {
+	ps, client := newTestParamStore()
+
+	seed := make(map[string]string)
+	for i := 0; i < 25; i++ {
+		seed[fmt.Sprintf("%s/KEY_%02d", testPath, i)] = fmt.Sprintf("value%d", i)
+	}
+	seed["/global/project1/prod/OTHER"] = "not under the path"
+	client.Seed(seed)
+
+	params, err := ps.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+
+	if len(params) != 25 {
+		t.Errorf("expected 25 parameters, but got %d", len(params))
+	}
+	if params["KEY_07"] != "value7" {
+		t.Errorf("expected KEY_07=value7, but got %q", params["KEY_07"])
+	}
+	if calls := client.Calls["GetParametersByPath"]; calls != 3 {
+		t.Errorf("expected 3 pages, but got %d calls", calls)
+	}
+}
+
+func TestGetParametersRecursive(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.KeyMapping = KeyMapping{Separator: "_", Case: "upper"}
+	client.Seed(map[string]string{
+		testPath + "/db/host":    "db.internal",
+		testPath + "/cache/host": "cache.internal",
+		testPath + "/PORT":       "8080",
+	})
+
+	params, err := ps.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+
+	expected := map[string]string{"DB_HOST": "db.internal", "CACHE_HOST": "cache.internal", "PORT": "8080"}
+	if len(params) != len(expected) {
+		t.Errorf("expected %v, but got %v", expected, params)
+	}
+	for k, v := range expected {
+		if params[k] != v {
+			t.Errorf("expected %s=%q, but got %q", k, v, params[k])
+		}
+	}
+
+	// Writing a mapped key goes back to the nested name it was read from.
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "DB_HOST", Value: "db2.internal"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	if versions := client.Versions(testPath + "/db/host"); len(versions) != 2 {
+		t.Errorf("expected db/host to get a second version, but got %+v", versions)
+	}
+}
+
+func TestGetParametersByNameNested(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.KeyMapping = KeyMapping{Separator: "_", Case: "upper"}
+	client.Seed(map[string]string{
+		testPath + "/db/host": "db.internal",
+		testPath + "/PORT":    "8080",
+	})
+
+	params, missing, err := ps.GetParametersByName(context.Background(), []string{"DB_HOST", "PORT", "MISSING"})
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+	expected := map[string]string{"DB_HOST": "db.internal", "PORT": "8080"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, but got %v", expected, params)
+	}
+	if !reflect.DeepEqual(missing, []string{"MISSING"}) {
+		t.Errorf("expected only MISSING to be missing, but got %v", missing)
+	}
+
+	if _, err := ps.PutParameter(context.Background(), Parameter{Key: "DB_HOST", Value: "db2.internal"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	if versions := client.Versions(testPath + "/db/host"); len(versions) != 2 {
+		t.Errorf("expected db/host to get a second version, but got %+v", versions)
+	}
+}
+
+func TestKeyMappingKey(t *testing.T) {
+	tests := []struct {
+		mapping  KeyMapping
+		relative string
+		expected string
+	}{
+		{KeyMapping{}, "PORT", "PORT"},
+		{KeyMapping{Case: "upper"}, "db/host", "DB_HOST"},
+		{KeyMapping{Case: "upper"}, "api-gateway/base.url", "API_GATEWAY_BASE_URL"},
+		{KeyMapping{Separator: "__", Case: "lower"}, "Cache/Redis-Host", "cache__redis__host"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mapping.Key(tt.relative); got != tt.expected {
+			t.Errorf("expected %s to map to %s, but got %s", tt.relative, tt.expected, got)
+		}
+	}
+}
+
+func TestGetParametersCollision(t *testing.T) {
+	ps, client := newTestParamStore()
+	ps.KeyMapping = KeyMapping{Case: "upper"}
+	client.Seed(map[string]string{
+		testPath + "/db/host": "a",
+		testPath + "/DB_HOST": "b",
+	})
+
+	_, err := ps.GetParameters(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "DB_HOST") {
+		t.Errorf("expected a collision on DB_HOST, but got %v", err)
+	}
+}
+
+func TestGetParametersByName(t *testing.T) {
+	ps, client := newTestParamStore()
+	client.Seed(map[string]string{
+		testPath + "/A": "1",
+		testPath + "/B": "2",
+	})
+
+	keys := []string{"A", "B"}
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("MISSING_%d", i))
+	}
+
+	params, missing, err := ps.GetParametersByName(context.Background(), keys)
+	if err != nil {
+		t.Fatalf("unexpected error getting parameters: %v", err)
+	}
+
+	if len(params) != 2 || params["A"] != "1" || params["B"] != "2" {
+		t.Errorf("expected A and B, but got %v", params)
+	}
+	if len(missing) != 10 {
+		t.Errorf("expected 10 missing keys, but got %v", missing)
+	}
+	if calls := client.Calls["GetParameters"]; calls != 2 {
+		t.Errorf("expected 2 batches, but got %d calls", calls)
+	}
+}
+
+func TestDeleteParameters(t *testing.T) {
+	ps, client := newTestParamStore()
+
+	seed := make(map[string]string)
+	var keys []string
+	for i := 0; i < 23; i++ {
+		key := fmt.Sprintf("KEY_%02d", i)
+		seed[testPath+"/"+key] = "value"
+		keys = append(keys, key)
+	}
+	client.Seed(seed)
+
+	deleted, failed := ps.DeleteParameters(context.Background(), append(keys, "MISSING"))
+
+	if len(deleted) != 23 {
+		t.Errorf("expected 23 deleted parameters, but got %d", len(deleted))
+	}
+	if _, ok := failed["MISSING"]; !ok || len(failed) != 1 {
+		t.Errorf("expected only MISSING to fail, but got %v", failed)
+	}
+	if calls := client.Calls["DeleteParameters"]; calls != 3 {
+		t.Errorf("expected 3 batches, but got %d calls", calls)
+	}
+	if names := client.Names(); len(names) != 0 {
+		t.Errorf("expected no parameters left, but got %v", names)
+	}
+}
+
+func TestGetParameterHistory(t *testing.T) {
+	ps, _ := newTestParamStore()
+
+	for _, v := range []string{"one", "two", "three"} {
+		if _, err := ps.PutParameter(context.Background(), Parameter{Key: "KEY", Value: v}, true); err != nil {
+			t.Fatalf("unexpected error putting parameter: %v", err)
+		}
+	}
+
+	versions, err := ps.GetParameterHistory(context.Background(), "KEY")
+	if err != nil {
+		t.Fatalf("unexpected error getting history: %v", err)
+	}
+
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, but got %d", len(versions))
+	}
+	if versions[0].Version != 1 || versions[0].Value != "one" || versions[2].Value != "three" {
+		t.Errorf("expected versions oldest first, but got %+v", versions)
+	}
+	if versions[0].Type != string(types.ParameterTypeSecureString) {
+		t.Errorf("expected SecureString versions, but got %s", versions[0].Type)
+	}
+	if !versions[0].LastModified.Before(versions[2].LastModified) {
+		t.Errorf("expected later versions to have later dates")
+	}
+
+	if _, err := ps.GetParameterHistory(context.Background(), "MISSING"); err == nil {
+		t.Errorf("expected error for missing parameter, but got none")
+	}
+}
+
+func TestFormatParamsAsEnv(t *testing.T) {
+	env := FormatParamsAsEnv(map[string]string{"A": "1", "B": "x=y"})
+	sort.Strings(env)
+
+	expected := "A=1,B=x=y"
+	if got := strings.Join(env, ","); got != expected {
+		t.Errorf("expected %s, but got %s", expected, got)
+	}
+}
+
+func TestLoadAWSConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config")
+	profiles := "[profile billing]\nregion = eu-west-1\n"
+	if err := os.WriteFile(configFile, []byte(profiles), 0600); err != nil {
+		t.Fatalf("unable to write AWS config file: %v", err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+
+	cfg, err := loadAWSConfig(context.Background(), AWSOptions{Profile: "billing"})
+	if err != nil {
+		t.Fatalf("unexpected error loading profile: %v", err)
+	}
+	if cfg.Region != "eu-west-1" {
+		t.Errorf("expected the profile's region eu-west-1, but got %s", cfg.Region)
+	}
+	retryer := cfg.Retryer()
+	if attempts := retryer.MaxAttempts(); attempts != retry.DefaultMaxAttempts {
+		t.Errorf("expected %d attempts, but got %d", retry.DefaultMaxAttempts, attempts)
+	}
+	if retryer.IsErrorRetryable(&smithy.GenericAPIError{Code: "ThrottlingException"}) {
+		t.Errorf("expected clients to leave throttles to callPaced")
+	}
+	if !retryer.IsErrorRetryable(&smithy.GenericAPIError{Code: "RequestTimeoutException"}) {
+		t.Errorf("expected clients to retry a request timeout")
+	}
+
+	cfg, err = loadAWSConfig(context.Background(), AWSOptions{
+		Region:     "eu-central-1",
+		Profile:    "billing",
+		RoleARN:    "arn:aws:iam::123456789012:role/ime",
+		ExternalID: "ext-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error loading role: %v", err)
+	}
+	if cfg.Region != "eu-central-1" {
+		t.Errorf("expected region eu-central-1 to win over the profile, but got %s", cfg.Region)
+	}
+	cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+	if !ok || !cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}) {
+		t.Errorf("expected assume role credentials, but got %T", cfg.Credentials)
+	}
+
+	if _, err := loadAWSConfig(context.Background(), AWSOptions{Profile: "missing"}); err == nil {
+		t.Errorf("expected error for missing profile, but got none")
+	}
+}
+
+func TestNewParamStoreEndpoint(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+
+	tests := []struct {
+		configured string
+		envVar     string
+		expected   string
+	}{
+		{"", "", ""},
+		{"http://localhost:4566", "", "http://localhost:4566"},
+		{"http://localhost:4566", "http://localstack:4566", "http://localstack:4566"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv(EndpointEnvVar, tt.envVar)
+
+		ps, err := NewParamStore(context.Background(), testPath, AWSOptions{Region: "us-east-1", EndpointURL: tt.configured})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		endpoint := ps.SSMClient.(*ssm.Client).Options().BaseEndpoint
+		if got := aws.ToString(endpoint); got != tt.expected {
+			t.Errorf("expected endpoint %q, but got %q", tt.expected, got)
+		}
+	}
+}
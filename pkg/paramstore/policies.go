@@ -0,0 +1,100 @@
+package paramstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Policies are the SSM parameter policies put on a parameter. A zero field
+// sets no policy. SSM only takes policies on advanced parameters.
+type Policies struct {
+	// ExpiresAfter has SSM delete the parameter this long after it is written.
+	ExpiresAfter time.Duration
+	// NotifyBeforeExpiry has SSM send an EventBridge event this long before
+	// the parameter expires.
+	NotifyBeforeExpiry time.Duration
+	// NotifyIfUnchangedFor has SSM send an EventBridge event when the
+	// parameter has not changed for this long.
+	NotifyIfUnchangedFor time.Duration
+}
+
+func (p Policies) IsZero() bool {
+	return p == Policies{}
+}
+
+// KeyPolicies give the policies each key is written with: its entry in Keys,
+// or Default for keys without one.
+type KeyPolicies struct {
+	Default Policies
+	Keys    map[string]Policies
+}
+
+func (k KeyPolicies) For(key string) Policies {
+	if policies, ok := k.Keys[key]; ok {
+		return policies
+	}
+	return k.Default
+}
+
+type policy struct {
+	Type       string            `json:"Type"`
+	Version    string            `json:"Version"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// policyJSON returns p in the JSON form PutParameterInput.Policies takes. The
+// expiration is counted from now.
+func policyJSON(p Policies, now time.Time) string {
+	var policies []policy
+	if p.ExpiresAfter > 0 {
+		policies = append(policies, policy{
+			Type:       "Expiration",
+			Version:    "1.0",
+			Attributes: map[string]string{"Timestamp": now.Add(p.ExpiresAfter).UTC().Format(time.RFC3339)},
+		})
+	}
+	if p.NotifyBeforeExpiry > 0 {
+		amount, unit := policyUnits(p.NotifyBeforeExpiry)
+		policies = append(policies, policy{
+			Type:       "ExpirationNotification",
+			Version:    "1.0",
+			Attributes: map[string]string{"Before": amount, "Unit": unit},
+		})
+	}
+	if p.NotifyIfUnchangedFor > 0 {
+		amount, unit := policyUnits(p.NotifyIfUnchangedFor)
+		policies = append(policies, policy{
+			Type:       "NoChangeNotification",
+			Version:    "1.0",
+			Attributes: map[string]string{"After": amount, "Unit": unit},
+		})
+	}
+
+	out, _ := json.Marshal(policies)
+	return string(out)
+}
+
+// policyUnits writes d in days when it is a whole number of them, and in
+// hours otherwise.
+func policyUnits(d time.Duration) (string, string) {
+	if d%(24*time.Hour) == 0 {
+		return strconv.FormatInt(int64(d/(24*time.Hour)), 10), "Days"
+	}
+	return strconv.FormatInt(int64(d/time.Hour), 10), "Hours"
+}
+
+// policyViolations describes what SSM would reject about p.
+func policyViolations(p Policies) []string {
+	var violations []string
+	for _, d := range []time.Duration{p.NotifyBeforeExpiry, p.NotifyIfUnchangedFor} {
+		if d%time.Hour != 0 {
+			violations = append(violations, fmt.Sprintf("notifications are set in whole hours or days, got %s", d))
+		}
+	}
+	if p.NotifyBeforeExpiry > 0 && p.NotifyBeforeExpiry >= p.ExpiresAfter {
+		violations = append(violations, fmt.Sprintf("notify before expiry (%s) must be shorter than expires after (%s)", p.NotifyBeforeExpiry, p.ExpiresAfter))
+	}
+	return violations
+}
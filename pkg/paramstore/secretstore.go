@@ -0,0 +1,764 @@
+package paramstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+const BackendSecretsManager = "secretsmanager"
+
+// TypeSecretString is the type SecretStore reports for every key.
+const TypeSecretString = "SecretString"
+
+// MaxSecretSize is the largest value, in bytes, a secret can hold.
+const MaxSecretSize = 65536
+
+// SecretsManagerAPI is the part of the Secrets Manager client SecretStore
+// uses. Tests swap in an in-memory stand-in such as fakesecrets.Client.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+	CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error)
+	DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+	ListSecretVersionIds(ctx context.Context, params *secretsmanager.ListSecretVersionIdsInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretVersionIdsOutput, error)
+	UpdateSecretVersionStage(ctx context.Context, params *secretsmanager.UpdateSecretVersionStageInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.UpdateSecretVersionStageOutput, error)
+	TagResource(ctx context.Context, params *secretsmanager.TagResourceInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.TagResourceOutput, error)
+}
+
+// SecretStore keeps the parameters of an environment in a single Secrets
+// Manager secret, named after the environment path, holding a JSON object of
+// key to value. Every write reads the secret, changes the keys written and
+// puts the whole object back as a new version. JSON keys are turned into env
+// var names by KeyMapping, as SSM names are.
+//
+// Versions are numbered in their version ids, which SecretStore chooses as
+// it writes, and labels are staging labels, which belong to the whole secret.
+type SecretStore struct {
+	Client     SecretsManagerAPI
+	SecretName string
+	KeyMapping KeyMapping
+	// KMSKeyID encrypts the secret when it is created. Empty means
+	// aws/secretsmanager.
+	KMSKeyID string
+	// Tags are put on the secret on every write.
+	Tags map[string]string
+	// Filter narrows what GetParameters returns. Tags must all be on the
+	// secret, and Label selects the version carrying that staging label.
+	Filter      Filter
+	CallTimeout time.Duration
+
+	pacer *pacer
+	// names maps keys to the JSON keys they were read from, so a mapped key
+	// is written back where it came from.
+	names map[string]string
+	types map[string]string
+	// versionID is the version last read or written, which LabelParameter
+	// labels.
+	versionID string
+}
+
+func NewSecretStore(ctx context.Context, secretName string, awsOpts AWSOptions) (*SecretStore, error) {
+	cfg, err := loadAWSConfig(ctx, awsOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		if awsOpts.EndpointURL != "" {
+			o.BaseEndpoint = aws.String(awsOpts.EndpointURL)
+		}
+	})
+	return NewSecretStoreWithClient(client, secretName), nil
+}
+
+// NewSecretStoreWithClient returns a SecretStore that talks to client instead
+// of building a Secrets Manager client from the AWS configuration.
+func NewSecretStoreWithClient(client SecretsManagerAPI, secretName string) *SecretStore {
+	return &SecretStore{
+		Client:     client,
+		SecretName: secretName,
+		names:      make(map[string]string),
+		types:      make(map[string]string),
+		pacer:      &pacer{},
+	}
+}
+
+var _ Backend = (*SecretStore)(nil)
+
+func (s *SecretStore) Path() string {
+	return s.SecretName
+}
+
+// FormatParamName names key as a path below the secret, the way SSM names
+// parameters, using the JSON key it was read from. Secrets Manager itself has
+// no name for a single key.
+func (s *SecretStore) FormatParamName(key string) string {
+	if field, ok := s.names[key]; ok {
+		key = field
+	}
+	return fmt.Sprintf("%s/%s", s.SecretName, key)
+}
+
+func (s *SecretStore) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	return callPaced(ctx, s.pacer, s.CallTimeout, fn)
+}
+
+// secretFields is the JSON object a secret holds, each value kept as the
+// JSON text it was read as, so writes leave the keys they do not change as
+// they were.
+type secretFields map[string]json.RawMessage
+
+// readSecret returns the fields of the version of the secret carrying stage,
+// or the current version when stage is empty, and the version's id. A secret
+// that does not exist holds no fields, but a stage no version carries is an
+// error.
+func (s *SecretStore) readSecret(ctx context.Context, stage string) (secretFields, string, error) {
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(s.SecretName)}
+	if stage != "" {
+		input.VersionStage = aws.String(stage)
+	}
+
+	var result *secretsmanager.GetSecretValueOutput
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.Client.GetSecretValue(ctx, input)
+		return err
+	})
+
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) && stage == "" {
+		return make(secretFields), "", nil
+	}
+	if err != nil && stage != "" {
+		return nil, "", fmt.Errorf("Error reading secret %s with label %s: %w", s.SecretName, stage, err)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("Error reading secret %s: %w", s.SecretName, err)
+	}
+
+	fields, err := parseSecret(aws.ToString(result.SecretString))
+	if err != nil {
+		return nil, "", fmt.Errorf("secret %s: %w", s.SecretName, err)
+	}
+	return fields, aws.ToString(result.VersionId), nil
+}
+
+// readValues is readSecret with the fields expanded into values by key, and
+// records the JSON key each key was read from.
+func (s *SecretStore) readValues(ctx context.Context, stage string) (map[string]string, string, error) {
+	fields, versionID, err := s.readSecret(ctx, stage)
+	if err != nil {
+		return nil, "", err
+	}
+	values, names, err := fields.expand(s.KeyMapping)
+	if err != nil {
+		return nil, "", fmt.Errorf("secret %s: %w", s.SecretName, err)
+	}
+	s.names = names
+	return values, versionID, nil
+}
+
+func parseSecret(secret string) (secretFields, error) {
+	var fields secretFields
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("expected a JSON object of key to value")
+	}
+	return fields, nil
+}
+
+// ExpandSecret turns a JSON object secret into one value per key, named by
+// mapping. Strings are taken as they are, and numbers, booleans, arrays and
+// objects as their JSON text. null becomes an empty value. JSON keys that map
+// to the same key, or to a name env files can't hold, are an error.
+func ExpandSecret(secret string, mapping KeyMapping) (map[string]string, error) {
+	fields, err := parseSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	values, _, err := fields.expand(mapping)
+	return values, err
+}
+
+// expand returns the value of every field by its mapped key, and the JSON key
+// each mapped key came from.
+func (f secretFields) expand(mapping KeyMapping) (map[string]string, map[string]string, error) {
+	fieldNames := make([]string, 0, len(f))
+	for k := range f {
+		fieldNames = append(fieldNames, k)
+	}
+	sort.Strings(fieldNames)
+
+	names := make(map[string]string, len(f))
+	var collisions, invalid []string
+	for _, field := range fieldNames {
+		key := mapping.Key(field)
+		if !isEnvName(key) {
+			invalid = append(invalid, fmt.Sprintf("%s (as %s)", field, key))
+			continue
+		}
+		if other, seen := names[key]; seen {
+			collisions = append(collisions, fmt.Sprintf("%s and %s both map to %s", other, field, key))
+			continue
+		}
+		names[key] = field
+	}
+	if len(collisions) > 0 {
+		return nil, nil, fmt.Errorf("keys collide: %s", strings.Join(collisions, "; "))
+	}
+	if len(invalid) > 0 {
+		return nil, nil, fmt.Errorf("keys are not valid env var names: %s", strings.Join(invalid, ", "))
+	}
+
+	values := make(map[string]string, len(f))
+	for key, field := range names {
+		raw := f[field]
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", field, err)
+		}
+
+		switch v := v.(type) {
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		case nil:
+			values[key] = ""
+		default:
+			text, err := json.Marshal(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", field, err)
+			}
+			values[key] = string(text)
+		}
+	}
+	return values, names, nil
+}
+
+// isEnvName reports whether key is a name env files accept: letters, digits
+// and underscores, not starting with a digit.
+func isEnvName(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// set stores value as a JSON string under key.
+func (f secretFields) set(key, value string) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	f[key] = raw
+	return nil
+}
+
+func (s *SecretStore) record(values map[string]string, versionID string) {
+	for k := range values {
+		s.types[k] = TypeSecretString
+	}
+	s.versionID = versionID
+}
+
+// GetParameters returns every key of the secret, narrowed by Filter.
+func (s *SecretStore) GetParameters(ctx context.Context) (map[string]string, error) {
+	if len(s.Filter.Tags) > 0 {
+		tagged, err := s.hasTags(ctx, s.Filter.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if !tagged {
+			return make(map[string]string), nil
+		}
+	}
+
+	values, versionID, err := s.readValues(ctx, s.Filter.Label)
+	if err != nil {
+		return nil, err
+	}
+	s.record(values, versionID)
+	return values, nil
+}
+
+// hasTags reports whether the secret carries every one of tags.
+func (s *SecretStore) hasTags(ctx context.Context, tags map[string]string) (bool, error) {
+	result, err := s.describe(ctx)
+	if err != nil || result == nil {
+		return false, err
+	}
+
+	found := make(map[string]string, len(result.Tags))
+	for _, tag := range result.Tags {
+		found[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for k, v := range tags {
+		if value, ok := found[k]; !ok || value != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// describe returns the secret's metadata, or nil when it does not exist.
+func (s *SecretStore) describe(ctx context.Context) (*secretsmanager.DescribeSecretOutput, error) {
+	var result *secretsmanager.DescribeSecretOutput
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.Client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(s.SecretName)})
+		return err
+	})
+
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error describing secret %s: %w", s.SecretName, err)
+	}
+	return result, nil
+}
+
+// GetParametersByName returns the given keys from a single read of the
+// secret, and the keys it does not hold.
+func (s *SecretStore) GetParametersByName(ctx context.Context, keys []string) (map[string]string, []string, error) {
+	values, versionID, err := s.readValues(ctx, s.Filter.Label)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := make(map[string]string, len(keys))
+	var missing []string
+	for _, k := range keys {
+		v, ok := values[k]
+		if !ok {
+			missing = append(missing, k)
+			continue
+		}
+		params[k] = v
+	}
+	s.record(params, versionID)
+	return params, missing, nil
+}
+
+func (s *SecretStore) PutParameter(ctx context.Context, param Parameter, overwrite bool) (int64, error) {
+	versions, err := s.PutParameters(ctx, []Parameter{param}, overwrite)
+
+	var putErr *PutError
+	if errors.As(err, &putErr) {
+		return 0, putErr.Failed[param.Key]
+	}
+	if err != nil {
+		return 0, err
+	}
+	return versions[param.Key], nil
+}
+
+// PutParameters writes every param in one new version of the secret. Keys
+// the secret already holds fail unless overwrite is set.
+func (s *SecretStore) PutParameters(ctx context.Context, params []Parameter, overwrite bool) (map[string]int64, error) {
+	changes := Changes{Creates: params}
+	if overwrite {
+		changes = Changes{Updates: params}
+	}
+
+	versions, _, failed := s.WriteChanges(ctx, changes)
+	if len(failed) > 0 {
+		return versions, &PutError{Failed: failed}
+	}
+	return versions, nil
+}
+
+// DeleteParameters removes keys from the secret in one new version.
+func (s *SecretStore) DeleteParameters(ctx context.Context, keys []string) ([]string, map[string]error) {
+	_, deleted, failed := s.WriteChanges(ctx, Changes{Deletes: keys})
+	return deleted, failed
+}
+
+var _ ChangeWriter = (*SecretStore)(nil)
+
+// WriteChanges reads the secret, applies changes and puts it back as one new
+// version. Keys the changes leave alone keep the JSON they hold.
+func (s *SecretStore) WriteChanges(ctx context.Context, changes Changes) (map[string]int64, []string, map[string]error) {
+	failed := make(map[string]error)
+
+	fields, versionID, err := s.readSecret(ctx, "")
+	if err != nil {
+		for _, param := range changes.Creates {
+			failed[param.Key] = err
+		}
+		for _, param := range changes.Updates {
+			failed[param.Key] = err
+		}
+		for _, k := range changes.Deletes {
+			failed[k] = err
+		}
+		return nil, nil, failed
+	}
+	_, names, err := fields.expand(s.KeyMapping)
+	if err != nil {
+		err = fmt.Errorf("secret %s: %w", s.SecretName, err)
+		for _, param := range changes.Creates {
+			failed[param.Key] = err
+		}
+		for _, param := range changes.Updates {
+			failed[param.Key] = err
+		}
+		for _, k := range changes.Deletes {
+			failed[k] = err
+		}
+		return nil, nil, failed
+	}
+	s.names = names
+
+	// field returns the JSON key a key is stored under: the one it was read
+	// from, or the key itself for a new one.
+	field := func(key string) string {
+		if f, ok := names[key]; ok {
+			return f
+		}
+		return key
+	}
+
+	var written, deleted []string
+	for _, param := range changes.Creates {
+		if _, ok := names[param.Key]; ok {
+			failed[param.Key] = fmt.Errorf("parameter %s already exists in secret %s", param.Key, s.SecretName)
+			continue
+		}
+		if err := fields.set(field(param.Key), param.Value); err != nil {
+			failed[param.Key] = err
+			continue
+		}
+		written = append(written, param.Key)
+	}
+	for _, param := range changes.Updates {
+		if err := fields.set(field(param.Key), param.Value); err != nil {
+			failed[param.Key] = err
+			continue
+		}
+		written = append(written, param.Key)
+	}
+	for _, k := range changes.Deletes {
+		if _, ok := names[k]; !ok {
+			failed[k] = fmt.Errorf("parameter %s was not deleted: not found in secret %s", k, s.SecretName)
+			continue
+		}
+		delete(fields, names[k])
+		deleted = append(deleted, k)
+	}
+
+	versions := make(map[string]int64)
+	if len(written) == 0 && len(deleted) == 0 {
+		return versions, nil, failed
+	}
+
+	version, err := s.writeSecret(ctx, fields, versionID != "")
+	if err != nil {
+		for _, k := range append(written, deleted...) {
+			failed[k] = err
+		}
+		return versions, nil, failed
+	}
+	for _, k := range written {
+		versions[k] = version
+		s.types[k] = TypeSecretString
+	}
+	return versions, deleted, failed
+}
+
+// writeSecret puts fields as a new version of the secret, creating it when
+// it does not exist, and returns the new version's number.
+func (s *SecretStore) writeSecret(ctx context.Context, fields secretFields, exists bool) (int64, error) {
+	secret, err := json.Marshal(fields)
+	if err != nil {
+		return 0, err
+	}
+	if len(secret) > MaxSecretSize {
+		return 0, fmt.Errorf("secret %s would be %d bytes, at most %d are allowed", s.SecretName, len(secret), MaxSecretSize)
+	}
+
+	version := int64(1)
+	if exists {
+		versions, err := s.listVersions(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for _, v := range versions {
+			if n, ok := versionNumber(aws.ToString(v.VersionId)); ok && n >= version {
+				version = n + 1
+			}
+		}
+	}
+	versionID, err := newVersionID(version)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.call(ctx, func(ctx context.Context) error {
+		if !exists {
+			input := &secretsmanager.CreateSecretInput{
+				Name:               aws.String(s.SecretName),
+				SecretString:       aws.String(string(secret)),
+				ClientRequestToken: aws.String(versionID),
+				Tags:               s.buildTags(),
+			}
+			if s.KMSKeyID != "" {
+				input.KmsKeyId = aws.String(s.KMSKeyID)
+			}
+			_, err := s.Client.CreateSecret(ctx, input)
+			return err
+		}
+
+		_, err := s.Client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+			SecretId:           aws.String(s.SecretName),
+			SecretString:       aws.String(string(secret)),
+			ClientRequestToken: aws.String(versionID),
+		})
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Error writing secret %s: %w", s.SecretName, err)
+	}
+	s.versionID = versionID
+
+	// New secrets are tagged as they are created, existing ones separately.
+	if exists && len(s.Tags) > 0 {
+		err := s.call(ctx, func(ctx context.Context) error {
+			_, err := s.Client.TagResource(ctx, &secretsmanager.TagResourceInput{
+				SecretId: aws.String(s.SecretName),
+				Tags:     s.buildTags(),
+			})
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("Error tagging secret %s: %w", s.SecretName, err)
+		}
+	}
+	return version, nil
+}
+
+// versionIDPrefix starts the id of every version SecretStore writes. The
+// version's number follows, so numbers stay the same when Secrets Manager
+// prunes old versions.
+const versionIDPrefix = "ime-v"
+
+// newVersionID returns a unique version id carrying number, for use as the
+// ClientRequestToken of a write.
+func newVersionID(number int64) (string, error) {
+	suffix := make([]byte, 12)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("Error generating a version id: %w", err)
+	}
+	return fmt.Sprintf("%s%012d-%x", versionIDPrefix, number, suffix), nil
+}
+
+// versionNumber returns the number in a version id from newVersionID. Versions
+// written by other tools have none.
+func versionNumber(versionID string) (int64, bool) {
+	rest, ok := strings.CutPrefix(versionID, versionIDPrefix)
+	if !ok {
+		return 0, false
+	}
+	digits, _, _ := strings.Cut(rest, "-")
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// buildTags returns Tags sorted by key, or nil when there are none.
+func (s *SecretStore) buildTags() []types.Tag {
+	keys := make([]string, 0, len(s.Tags))
+	for k := range s.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tags []types.Tag
+	for _, k := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(s.Tags[k])})
+	}
+	return tags
+}
+
+// listVersions returns every version of the secret, oldest first.
+func (s *SecretStore) listVersions(ctx context.Context) ([]types.SecretVersionsListEntry, error) {
+	var versions []types.SecretVersionsListEntry
+	input := &secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(s.SecretName),
+		IncludeDeprecated: aws.Bool(true),
+	}
+
+	for {
+		var result *secretsmanager.ListSecretVersionIdsOutput
+		err := s.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = s.Client.ListSecretVersionIds(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error listing versions of secret %s: %w", s.SecretName, err)
+		}
+
+		versions = append(versions, result.Versions...)
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return aws.ToTime(versions[i].CreatedDate).Before(aws.ToTime(versions[j].CreatedDate))
+	})
+	return versions, nil
+}
+
+// Validate checks that params alone fit in a secret. Keys already in the
+// secret count too, which PutParameters checks before writing.
+func (s *SecretStore) Validate(params []Parameter) error {
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		values[param.Key] = param.Value
+	}
+
+	secret, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if len(secret) > MaxSecretSize {
+		return &ValidationError{Violations: []string{
+			fmt.Sprintf("values add up to %d bytes as JSON, secret %s holds at most %d", len(secret), s.SecretName, MaxSecretSize),
+		}}
+	}
+	return nil
+}
+
+// GetParameterHistory returns the value of key in every version of the
+// secret holding it, oldest first. Versions written by other tools carry no
+// number and are left out.
+func (s *SecretStore) GetParameterHistory(ctx context.Context, key string) ([]ParameterVersion, error) {
+	versions, err := s.listVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []ParameterVersion
+	for _, v := range versions {
+		number, ok := versionNumber(aws.ToString(v.VersionId))
+		if !ok {
+			continue
+		}
+
+		var result *secretsmanager.GetSecretValueOutput
+		err := s.call(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = s.Client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+				SecretId:  aws.String(s.SecretName),
+				VersionId: v.VersionId,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error getting parameter history for %s: %w", key, err)
+		}
+
+		values, err := ExpandSecret(aws.ToString(result.SecretString), s.KeyMapping)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s version %d: %w", s.SecretName, number, err)
+		}
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		history = append(history, ParameterVersion{
+			Version:      number,
+			Value:        value,
+			Type:         TypeSecretString,
+			LastModified: aws.ToTime(v.CreatedDate),
+			Labels:       v.VersionStages,
+		})
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("parameter %s not found in secret %s", key, s.SecretName)
+	}
+	return history, nil
+}
+
+// LabelParameter moves the staging label to the version of the secret last
+// read, or to its current version. The label applies to every key.
+func (s *SecretStore) LabelParameter(ctx context.Context, key, label string) error {
+	result, err := s.describe(ctx)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return fmt.Errorf("Error labeling parameter %s: secret %s not found", key, s.SecretName)
+	}
+
+	target := s.versionID
+	var current string
+	for id, stages := range result.VersionIdsToStages {
+		for _, stage := range stages {
+			if stage == label {
+				current = id
+			}
+			if stage == "AWSCURRENT" && target == "" {
+				target = id
+			}
+		}
+	}
+	if current == target {
+		return nil
+	}
+
+	input := &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:        aws.String(s.SecretName),
+		VersionStage:    aws.String(label),
+		MoveToVersionId: aws.String(target),
+	}
+	if current != "" {
+		input.RemoveFromVersionId = aws.String(current)
+	}
+
+	err = s.call(ctx, func(ctx context.Context) error {
+		_, err := s.Client.UpdateSecretVersionStage(ctx, input)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error labeling parameter %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *SecretStore) ParameterTypes() map[string]string {
+	return s.types
+}
@@ -0,0 +1,315 @@
+package paramstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/paramstore/fakesecrets"
+)
+
+func newTestSecretStore() (*SecretStore, *fakesecrets.Client) {
+	client := fakesecrets.New()
+	return NewSecretStoreWithClient(client, testPath), client
+}
+
+func TestSecretStoreExpandsJSON(t *testing.T) {
+	ss, client := newTestSecretStore()
+	client.Seed(testPath, `{"DB_HOST":"db.internal","PORT":5432,"DEBUG":true,"OPTIONS":{"ssl":true},"EMPTY":null}`)
+
+	params, err := ss.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"DB_HOST": "db.internal",
+		"PORT":    "5432",
+		"DEBUG":   "true",
+		"OPTIONS": `{"ssl":true}`,
+		"EMPTY":   "",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, but got %v", expected, params)
+	}
+
+	client.Seed(testPath, "not json")
+	if _, err := ss.GetParameters(context.Background()); err == nil {
+		t.Errorf("expected error for a secret that is not a JSON object, but got none")
+	}
+}
+
+func TestSecretStoreKeyMapping(t *testing.T) {
+	ss, client := newTestSecretStore()
+	ss.KeyMapping = KeyMapping{Case: "upper"}
+	client.Seed(testPath, `{"db-host":"db.internal","db.port":5432}`)
+
+	params, err := ss.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"DB_HOST": "db.internal", "DB_PORT": "5432"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, but got %v", expected, params)
+	}
+
+	// Writing a mapped key goes back to the JSON key it was read from.
+	if _, err := ss.PutParameter(context.Background(), Parameter{Key: "DB_HOST", Value: "db2.internal"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	stored := client.Versions(testPath)
+	if got := stored[len(stored)-1].Value; got != `{"db-host":"db2.internal","db.port":5432}` {
+		t.Errorf("expected db-host to be updated in place, but got %s", got)
+	}
+
+	tests := []struct {
+		secret   string
+		expected string
+	}{
+		{`{"db-host":"a","db_host":"b"}`, "db-host and db_host both map to DB_HOST"},
+		{`{"2fa-secret":"x"}`, "2fa-secret"},
+	}
+	for _, tt := range tests {
+		client.Seed(testPath, tt.secret)
+		_, err := ss.GetParameters(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("expected an error naming %s, but got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestSecretStoreMissingSecret(t *testing.T) {
+	ss, _ := newTestSecretStore()
+
+	params, err := ss.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(params) != 0 {
+		t.Errorf("expected no parameters, but got %v", params)
+	}
+}
+
+func TestSecretStoreMissingLabel(t *testing.T) {
+	ss, client := newTestSecretStore()
+	client.Seed(testPath, `{"KEY":"value"}`)
+	ss.Filter = Filter{Label: "release-42"}
+
+	if _, err := ss.GetParameters(context.Background()); err == nil {
+		t.Errorf("expected error for a label no version carries, but got none")
+	}
+	if _, _, err := ss.GetParametersByName(context.Background(), []string{"KEY"}); err == nil {
+		t.Errorf("expected error for a label no version carries, but got none")
+	}
+}
+
+func TestSecretStorePutParameters(t *testing.T) {
+	ss, client := newTestSecretStore()
+	ss.KMSKeyID = "alias/prod"
+	ss.Tags = map[string]string{"managed-by": "ime"}
+
+	versions, err := ss.PutParameters(context.Background(), []Parameter{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}}, false)
+	if err != nil {
+		t.Fatalf("unexpected error creating the secret: %v", err)
+	}
+	if versions["A"] != 1 || versions["B"] != 1 {
+		t.Errorf("expected both keys at version 1, but got %v", versions)
+	}
+	if client.Calls["CreateSecret"] != 1 || client.Tags(testPath)["managed-by"] != "ime" {
+		t.Errorf("expected the secret to be created once with its tags, but got %d creates and tags %v", client.Calls["CreateSecret"], client.Tags(testPath))
+	}
+
+	// Existing keys fail without overwrite, while new keys are still written.
+	versions, err = ss.PutParameters(context.Background(), []Parameter{{Key: "A", Value: "changed"}, {Key: "C", Value: "3"}}, false)
+	var putErr *PutError
+	if !errors.As(err, &putErr) || len(putErr.Failed) != 1 || putErr.Failed["A"] == nil {
+		t.Fatalf("expected only A to fail, but got %v", err)
+	}
+	if versions["C"] != 2 {
+		t.Errorf("expected C at version 2, but got %v", versions)
+	}
+
+	if _, err := ss.PutParameter(context.Background(), Parameter{Key: "A", Value: "changed"}, true); err != nil {
+		t.Fatalf("unexpected error overwriting: %v", err)
+	}
+
+	stored := client.Versions(testPath)
+	if len(stored) != 3 || stored[2].Value != `{"A":"changed","B":"2","C":"3"}` {
+		t.Errorf("expected 3 versions ending with every key, but got %+v", stored)
+	}
+}
+
+func TestSecretStoreKeepsUnchangedJSON(t *testing.T) {
+	ss, client := newTestSecretStore()
+	client.Seed(testPath, `{"PORT":5432,"DEBUG":true,"OPTIONS":{"ssl":true},"RETIRED":"x"}`)
+
+	if _, err := ss.PutParameter(context.Background(), Parameter{Key: "DB_HOST", Value: "db.internal"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	if _, failed := ss.DeleteParameters(context.Background(), []string{"RETIRED"}); len(failed) > 0 {
+		t.Fatalf("unexpected error deleting parameter: %v", failed)
+	}
+
+	stored := client.Versions(testPath)
+	expected := `{"DB_HOST":"db.internal","DEBUG":true,"OPTIONS":{"ssl":true},"PORT":5432}`
+	if got := stored[len(stored)-1].Value; got != expected {
+		t.Errorf("expected untouched keys to keep their JSON %s, but got %s", expected, got)
+	}
+}
+
+func TestSecretStoreDeleteParameters(t *testing.T) {
+	ss, client := newTestSecretStore()
+	client.Seed(testPath, `{"KEEP":"1","RETIRED":"2"}`)
+
+	deleted, failed := ss.DeleteParameters(context.Background(), []string{"RETIRED", "MISSING"})
+	if !reflect.DeepEqual(deleted, []string{"RETIRED"}) {
+		t.Errorf("expected RETIRED to be deleted, but got %v", deleted)
+	}
+	if len(failed) != 1 || failed["MISSING"] == nil {
+		t.Errorf("expected MISSING to fail, but got %v", failed)
+	}
+
+	params, err := ss.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(params, map[string]string{"KEEP": "1"}) {
+		t.Errorf("expected only KEEP, but got %v", params)
+	}
+}
+
+func TestSecretStoreHistoryAndLabels(t *testing.T) {
+	ss, _ := newTestSecretStore()
+
+	for _, v := range []string{"one", "two"} {
+		if _, err := ss.PutParameter(context.Background(), Parameter{Key: "KEY", Value: v}, true); err != nil {
+			t.Fatalf("unexpected error putting parameter: %v", err)
+		}
+	}
+	if _, err := ss.PutParameter(context.Background(), Parameter{Key: "OTHER", Value: "x"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+
+	history, err := ss.GetParameterHistory(context.Background(), "KEY")
+	if err != nil {
+		t.Fatalf("unexpected error getting history: %v", err)
+	}
+	if len(history) != 3 || history[0].Value != "one" || history[1].Value != "two" || history[2].Version != 3 {
+		t.Errorf("expected KEY in all 3 versions, oldest first, but got %+v", history)
+	}
+
+	if err := ss.LabelParameter(context.Background(), "KEY", "release-42"); err != nil {
+		t.Fatalf("unexpected error labeling: %v", err)
+	}
+	if _, err := ss.PutParameter(context.Background(), Parameter{Key: "KEY", Value: "three"}, true); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+
+	pinned, _ := newTestSecretStore()
+	pinned.Client = ss.Client
+	pinned.Filter = Filter{Label: "release-42"}
+	params, err := pinned.GetParameters(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params["KEY"] != "two" {
+		t.Errorf("expected the labeled value two, but got %q", params["KEY"])
+	}
+
+	// Moving the label to a later version takes it off the earlier one.
+	if err := ss.LabelParameter(context.Background(), "KEY", "release-42"); err != nil {
+		t.Fatalf("unexpected error moving the label: %v", err)
+	}
+	if params, _ := pinned.GetParameters(context.Background()); params["KEY"] != "three" {
+		t.Errorf("expected the moved label to select three, but got %q", params["KEY"])
+	}
+}
+
+func TestSecretStoreVersionsSurvivePruning(t *testing.T) {
+	ss, client := newTestSecretStore()
+	client.MaxVersions = 3
+
+	for _, v := range []string{"one", "two", "three", "four", "five"} {
+		if _, err := ss.PutParameter(context.Background(), Parameter{Key: "KEY", Value: v}, true); err != nil {
+			t.Fatalf("unexpected error putting parameter: %v", err)
+		}
+	}
+
+	history, err := ss.GetParameterHistory(context.Background(), "KEY")
+	if err != nil {
+		t.Fatalf("unexpected error getting history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected the 3 versions left after pruning, but got %+v", history)
+	}
+	for i, expected := range []struct {
+		version int64
+		value   string
+	}{{3, "three"}, {4, "four"}, {5, "five"}} {
+		if history[i].Version != expected.version || history[i].Value != expected.value {
+			t.Errorf("expected version %d to hold %s, but got %+v", expected.version, expected.value, history[i])
+		}
+	}
+
+	version, err := ss.PutParameter(context.Background(), Parameter{Key: "KEY", Value: "six"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+	if version != 6 {
+		t.Errorf("expected the next write to be version 6, but got %d", version)
+	}
+}
+
+func TestSecretStoreWriteChanges(t *testing.T) {
+	ss, client := newTestSecretStore()
+	client.Seed(testPath, `{"EXISTING":"remote","RETIRED":"old"}`)
+
+	versions, deleted, failed := ss.WriteChanges(context.Background(), Changes{
+		Creates: []Parameter{{Key: "NEW", Value: "1"}, {Key: "EXISTING", Value: "clash"}},
+		Updates: []Parameter{{Key: "EXISTING", Value: "local"}},
+		Deletes: []string{"RETIRED"},
+	})
+	if len(failed) != 1 || failed["EXISTING"] == nil {
+		t.Errorf("expected only the create of EXISTING to fail, but got %v", failed)
+	}
+	if versions["NEW"] != versions["EXISTING"] || versions["NEW"] == 0 {
+		t.Errorf("expected NEW and EXISTING written in the same version, but got %v", versions)
+	}
+	if !reflect.DeepEqual(deleted, []string{"RETIRED"}) {
+		t.Errorf("expected RETIRED to be deleted, but got %v", deleted)
+	}
+
+	stored := client.Versions(testPath)
+	if len(stored) != 2 || stored[1].Value != `{"EXISTING":"local","NEW":"1"}` {
+		t.Errorf("expected the seed and one new version, but got %+v", stored)
+	}
+}
+
+func TestSecretStoreTagFilter(t *testing.T) {
+	ss, _ := newTestSecretStore()
+	ss.Tags = map[string]string{"team": "billing"}
+	if _, err := ss.PutParameter(context.Background(), Parameter{Key: "A", Value: "1"}, false); err != nil {
+		t.Fatalf("unexpected error putting parameter: %v", err)
+	}
+
+	tests := []struct {
+		tags     map[string]string
+		expected int
+	}{
+		{map[string]string{"team": "billing"}, 1},
+		{map[string]string{"team": "search"}, 0},
+	}
+
+	for _, tt := range tests {
+		ss.Filter = Filter{Tags: tt.tags}
+		params, err := ss.GetParameters(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(params) != tt.expected {
+			t.Errorf("expected %d parameters for tags %v, but got %v", tt.expected, tt.tags, params)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package paramstore
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// DefaultConcurrency is how many parameters PutParameters writes at once when
+// Concurrency is not set. SSM allows a few puts per second by default, so more
+// workers mostly buy more throttling.
+const DefaultConcurrency = 4
+
+// Backoff settings for throttled calls. They are variables so tests can
+// shorten them.
+var (
+	throttleBaseDelay = 200 * time.Millisecond
+	throttleMaxDelay  = 10 * time.Second
+	throttleAttempts  = 8
+)
+
+// pacer spaces out the calls of every worker sharing it. A throttled call
+// doubles the gap between calls and a successful one shrinks it again, so
+// the workers settle on the rate SSM accepts.
+type pacer struct {
+	mu   sync.Mutex
+	gap  time.Duration
+	next time.Time
+}
+
+func (pc *pacer) wait(ctx context.Context) error {
+	pc.mu.Lock()
+	at := pc.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	pc.next = at.Add(pc.gap)
+	pc.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+func (pc *pacer) throttled() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.gap *= 2
+	if pc.gap < throttleBaseDelay {
+		pc.gap = throttleBaseDelay
+	}
+	if pc.gap > throttleMaxDelay {
+		pc.gap = throttleMaxDelay
+	}
+}
+
+func (pc *pacer) succeeded() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.gap -= pc.gap / 4
+	if pc.gap < time.Millisecond {
+		pc.gap = 0
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// isThrottle reports whether err is SSM or Secrets Manager asking to slow down.
+func isThrottle(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "Throttling", "TooManyUpdates", "RequestLimitExceeded":
+		return true
+	default:
+		return false
+	}
+}
+
+// call runs fn with the per-call timeout, retrying with jittered exponential
+// backoff for as long as SSM throttles it. Waiting stops as soon as ctx is
+// done.
+func (p *ParamStore) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	return callPaced(ctx, p.pacer, p.CallTimeout, fn)
+}
+
+// callPaced runs fn paced by pc, with timeout bounding each attempt. Zero
+// means DefaultCallTimeout.
+func callPaced(ctx context.Context, pc *pacer, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		timeout = DefaultCallTimeout
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := pc.wait(ctx); err != nil {
+			return err
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := fn(callCtx)
+		cancel()
+
+		if err == nil {
+			pc.succeeded()
+			return nil
+		}
+		if !isThrottle(err) || attempt == throttleAttempts {
+			return err
+		}
+		pc.throttled()
+
+		backoff := throttleBaseDelay << (attempt - 1)
+		if backoff > throttleMaxDelay {
+			backoff = throttleMaxDelay
+		}
+		backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package paramstore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Tiers a ParamStore can write parameters in.
+const (
+	TierStandard    = "standard"
+	TierAdvanced    = "advanced"
+	TierIntelligent = "intelligent"
+)
+
+// Limits SSM puts on parameters. Values are measured in bytes.
+const (
+	MaxStandardValueSize = 4096
+	MaxAdvancedValueSize = 8192
+	MaxNameLength        = 1011
+	MaxHierarchyLevels   = 15
+)
+
+// ValidationError lists every parameter the backend would reject, so they
+// can all be fixed before anything is written.
+type ValidationError struct {
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d problem(s) found before writing:\n  %s", len(e.Violations), strings.Join(e.Violations, "\n  "))
+}
+
+// sdkTier returns the SSM tier for a tier setting. Standard is left empty so
+// SSM keeps its default and never tries to downgrade an advanced parameter.
+func sdkTier(tier string) types.ParameterTier {
+	switch tier {
+	case TierAdvanced:
+		return types.ParameterTierAdvanced
+	case TierIntelligent:
+		return types.ParameterTierIntelligentTiering
+	default:
+		return ""
+	}
+}
+
+// Validate checks params against the SSM limits on names, hierarchy depth,
+// value size for the configured tier and parameter policies. It returns a
+// *ValidationError listing every violation, or nil.
+func (p *ParamStore) Validate(params []Parameter) error {
+	var violations []string
+	for _, param := range params {
+		name := p.FormatParamName(param.Key)
+		policies := p.Policies.For(param.Key)
+
+		// Parameters with policies are always written as advanced.
+		maxValueSize := MaxStandardValueSize
+		if p.Tier == TierAdvanced || p.Tier == TierIntelligent || !policies.IsZero() {
+			maxValueSize = MaxAdvancedValueSize
+		}
+
+		if len(name) > MaxNameLength {
+			violations = append(violations, fmt.Sprintf("%s: name is %d characters, at most %d are allowed", param.Key, len(name), MaxNameLength))
+		}
+
+		segments := strings.Split(strings.Trim(name, "/"), "/")
+		if len(segments) > MaxHierarchyLevels {
+			violations = append(violations, fmt.Sprintf("%s: %s has %d levels, at most %d are allowed", param.Key, name, len(segments), MaxHierarchyLevels))
+		}
+
+		first := strings.ToLower(segments[0])
+		if strings.HasPrefix(first, "aws") || strings.HasPrefix(first, "ssm") {
+			violations = append(violations, fmt.Sprintf("%s: %s starts with aws or ssm, which are reserved", param.Key, name))
+		}
+
+		if i := strings.IndexFunc(name, func(r rune) bool { return !validNameRune(r) }); i >= 0 {
+			violations = append(violations, fmt.Sprintf("%s: %s contains %q, only letters, digits and . - _ / are allowed", param.Key, name, name[i]))
+		}
+
+		switch size := len(param.Value); {
+		case size == 0:
+			violations = append(violations, fmt.Sprintf("%s: value is empty, SSM requires at least one character", param.Key))
+		case size > maxValueSize && maxValueSize == MaxStandardValueSize:
+			violations = append(violations, fmt.Sprintf("%s: value is %d bytes, the standard tier allows %d, set tier: advanced or intelligent", param.Key, size, maxValueSize))
+		case size > maxValueSize:
+			violations = append(violations, fmt.Sprintf("%s: value is %d bytes, at most %d are allowed", param.Key, size, maxValueSize))
+		}
+
+		for _, v := range policyViolations(policies) {
+			violations = append(violations, fmt.Sprintf("%s: %s", param.Key, v))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func validNameRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == '.', r == '-', r == '_', r == '/':
+		return true
+	default:
+		return false
+	}
+}
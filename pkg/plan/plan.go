@@ -0,0 +1,260 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pytoolbelt/ime/pkg/environment"
+)
+
+const (
+	ModeAdd    = "add"
+	ModeDelete = "delete"
+	ModeMerge  = "merge"
+)
+
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionDelete    Action = "delete"
+	ActionUnchanged Action = "unchanged"
+	// ActionSkip is a key that differs but that the mode leaves alone.
+	ActionSkip Action = "skip"
+	// ActionPull and ActionConflict only appear in merge plans. A pull writes
+	// the remote value into the env file, or removes the key when Remove is set.
+	ActionPull     Action = "pull"
+	ActionConflict Action = "conflict"
+)
+
+type Change struct {
+	Key    string `json:"key"`
+	Action Action `json:"action"`
+	Value  string `json:"value,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Remove bool   `json:"remove,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Plan is the full changeset of a push, computed before anything is written.
+// RemoteFingerprint and LocalFingerprint identify the state the plan was made
+// against, so a saved plan can refuse to run once either side has moved on.
+type Plan struct {
+	Project           string   `json:"project"`
+	Env               string   `json:"env"`
+	Path              string   `json:"path"`
+	Mode              string   `json:"mode"`
+	RemoteFingerprint string   `json:"remote_fingerprint"`
+	LocalFingerprint  string   `json:"local_fingerprint"`
+	Changes           []Change `json:"changes"`
+}
+
+// Add plans the creation of every local key missing from remote. Keys that
+// exist with another value are updated only when overwrite is set.
+func Add(local map[string]string, remote map[string]string, overwrite bool) []Change {
+	var changes []Change
+
+	for _, key := range unionKeys(local, remote) {
+		localValue, inLocal := local[key]
+		remoteValue, inRemote := remote[key]
+
+		switch {
+		case !inLocal:
+			changes = append(changes, Change{Key: key, Action: ActionUnchanged})
+		case !inRemote:
+			changes = append(changes, Change{Key: key, Action: ActionCreate, Value: localValue})
+		case localValue == remoteValue:
+			changes = append(changes, Change{Key: key, Action: ActionUnchanged})
+		case overwrite:
+			changes = append(changes, Change{Key: key, Action: ActionUpdate, Value: localValue})
+		default:
+			changes = append(changes, Change{Key: key, Action: ActionSkip, Reason: "exists with another value, use --overwrite"})
+		}
+	}
+	return changes
+}
+
+// Delete plans the removal of every remote key missing from local or, when
+// listed is set, of every remote key present in local.
+func Delete(local map[string]string, remote map[string]string, listed bool) []Change {
+	var changes []Change
+
+	for _, key := range sortedKeys(remote) {
+		if _, inLocal := local[key]; inLocal == listed {
+			changes = append(changes, Change{Key: key, Action: ActionDelete})
+		} else {
+			changes = append(changes, Change{Key: key, Action: ActionUnchanged})
+		}
+	}
+	return changes
+}
+
+// Merge plans a three-way merge between local, remote and the baseline recorded
+// the last time they agreed. A key changed on only one side is copied to the
+// other; a key changed on both sides is a conflict.
+func Merge(local map[string]string, remote map[string]string, base *environment.Baseline) []Change {
+	keys := unionKeys(local, remote)
+	for key := range base.Hashes {
+		if _, inLocal := local[key]; !inLocal {
+			if _, inRemote := remote[key]; !inRemote {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []Change
+
+	for _, key := range keys {
+		localValue, inLocal := local[key]
+		remoteValue, inRemote := remote[key]
+
+		switch {
+		case inLocal == inRemote && localValue == remoteValue:
+			changes = append(changes, Change{Key: key, Action: ActionUnchanged})
+
+		case base.Matches(key, localValue, inLocal):
+			// Only the parameter store changed, bring the env file up to date.
+			changes = append(changes, Change{Key: key, Action: ActionPull, Value: remoteValue, Remove: !inRemote})
+
+		case base.Matches(key, remoteValue, inRemote):
+			// Only the env file changed, push it to the parameter store.
+			switch {
+			case !inLocal:
+				changes = append(changes, Change{Key: key, Action: ActionDelete})
+			case !inRemote:
+				changes = append(changes, Change{Key: key, Action: ActionCreate, Value: localValue})
+			default:
+				changes = append(changes, Change{Key: key, Action: ActionUpdate, Value: localValue})
+			}
+
+		default:
+			reason := fmt.Sprintf("local %s, remote %s", describeChange(base, key, inLocal), describeChange(base, key, inRemote))
+			changes = append(changes, Change{Key: key, Action: ActionConflict, Reason: reason})
+		}
+	}
+	return changes
+}
+
+func describeChange(base *environment.Baseline, key string, present bool) string {
+	_, recorded := base.Hashes[key]
+	switch {
+	case !recorded && present:
+		return "added"
+	case recorded && !present:
+		return "deleted"
+	default:
+		return "changed"
+	}
+}
+
+// Fingerprint identifies a set of values without revealing them.
+func Fingerprint(values map[string]string) string {
+	h := sha256.New()
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(h, "%s=%s\n", key, environment.HashValue(values[key]))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetTypes sets the parameter type of every change that writes a parameter.
+func (p *Plan) SetTypes(typeOf func(key string) string) {
+	for i, c := range p.Changes {
+		if c.Action == ActionCreate || c.Action == ActionUpdate {
+			p.Changes[i].Type = typeOf(c.Key)
+		}
+	}
+}
+
+// Count returns how many changes have the given action.
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// HasChanges reports whether applying the plan would write anything.
+func (p *Plan) HasChanges() bool {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate, ActionUpdate, ActionDelete, ActionPull:
+			return true
+		}
+	}
+	return false
+}
+
+// PrintTable prints the changeset. Values are never shown.
+func (p *Plan) PrintTable() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Key", "Action", "Type", "Detail"})
+
+	for _, c := range p.Changes {
+		detail := c.Reason
+		if c.Action == ActionPull && c.Remove {
+			detail = "remove from env file"
+		}
+		table.Append([]string{c.Key, string(c.Action), c.Type, detail})
+	}
+
+	table.Render()
+	fmt.Printf("Plan for %s (%s): %d to create, %d to update, %d to delete, %d to pull, %d unchanged, %d skipped, %d conflicts \n",
+		p.Path, p.Mode, p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete), p.Count(ActionPull), p.Count(ActionUnchanged), p.Count(ActionSkip), p.Count(ActionConflict))
+}
+
+// Save writes the plan as JSON. The plan holds the values it will write, so
+// the file is only readable by its owner.
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write plan %s: %w", path, err)
+	}
+	return nil
+}
+
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read plan %s: %w", path, err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unable to parse plan %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unionKeys(a, b map[string]string) []string {
+	keys := sortedKeys(a)
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
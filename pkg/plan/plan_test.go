@@ -0,0 +1,123 @@
+package plan
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pytoolbelt/ime/pkg/environment"
+)
+
+func actions(changes []Change) map[string]Action {
+	result := make(map[string]Action)
+	for _, c := range changes {
+		result[c.Key] = c.Action
+	}
+	return result
+}
+
+func TestAdd(t *testing.T) {
+	local := map[string]string{"NEW": "1", "NEW_EMPTY": "", "SAME": "2", "CHANGED": "3"}
+	remote := map[string]string{"SAME": "2", "CHANGED": "old", "REMOTE_ONLY": "4"}
+
+	tests := []struct {
+		overwrite bool
+		expected  map[string]Action
+	}{
+		{false, map[string]Action{"NEW": ActionCreate, "NEW_EMPTY": ActionCreate, "SAME": ActionUnchanged, "CHANGED": ActionSkip, "REMOTE_ONLY": ActionUnchanged}},
+		{true, map[string]Action{"NEW": ActionCreate, "NEW_EMPTY": ActionCreate, "SAME": ActionUnchanged, "CHANGED": ActionUpdate, "REMOTE_ONLY": ActionUnchanged}},
+	}
+
+	for _, tt := range tests {
+		got := actions(Add(local, remote, tt.overwrite))
+		for key, action := range tt.expected {
+			if got[key] != action {
+				t.Errorf("overwrite=%v: expected %s for %s, but got %s", tt.overwrite, action, key, got[key])
+			}
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	local := map[string]string{"KEEP": "1", "LOCAL_ONLY": "2"}
+	remote := map[string]string{"KEEP": "1", "RETIRED": "3"}
+
+	tests := []struct {
+		listed   bool
+		expected map[string]Action
+	}{
+		{false, map[string]Action{"KEEP": ActionUnchanged, "RETIRED": ActionDelete}},
+		{true, map[string]Action{"KEEP": ActionDelete, "RETIRED": ActionUnchanged}},
+	}
+
+	for _, tt := range tests {
+		changes := Delete(local, remote, tt.listed)
+		if len(changes) != len(remote) {
+			t.Errorf("listed=%v: expected %d changes, but got %d", tt.listed, len(remote), len(changes))
+		}
+		got := actions(changes)
+		for key, action := range tt.expected {
+			if got[key] != action {
+				t.Errorf("listed=%v: expected %s for %s, but got %s", tt.listed, action, key, got[key])
+			}
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ef := environment.NewEnvFileFromPath(filepath.Join(t.TempDir(), ".env"))
+	base := environment.NewBaselineForEnvFile(ef)
+	for k, v := range map[string]string{
+		"SAME": "1", "LOCAL_EDIT": "1", "REMOTE_EDIT": "1", "BOTH_EDIT": "1",
+		"LOCAL_DELETE": "1", "REMOTE_DELETE": "1", "BOTH_DELETE": "1",
+	} {
+		base.Set(k, v)
+	}
+
+	local := map[string]string{
+		"SAME": "1", "LOCAL_EDIT": "2", "REMOTE_EDIT": "1", "BOTH_EDIT": "2",
+		"REMOTE_DELETE": "1", "LOCAL_NEW": "1",
+	}
+	remote := map[string]string{
+		"SAME": "1", "LOCAL_EDIT": "1", "REMOTE_EDIT": "2", "BOTH_EDIT": "3",
+		"LOCAL_DELETE": "1", "REMOTE_NEW": "1",
+	}
+
+	expected := map[string]Action{
+		"SAME":          ActionUnchanged,
+		"LOCAL_EDIT":    ActionUpdate,
+		"REMOTE_EDIT":   ActionPull,
+		"BOTH_EDIT":     ActionConflict,
+		"LOCAL_DELETE":  ActionDelete,
+		"REMOTE_DELETE": ActionPull,
+		"BOTH_DELETE":   ActionUnchanged,
+		"LOCAL_NEW":     ActionCreate,
+		"REMOTE_NEW":    ActionPull,
+	}
+
+	changes := Merge(local, remote, base)
+	got := actions(changes)
+	for key, action := range expected {
+		if got[key] != action {
+			t.Errorf("expected %s for %s, but got %s", action, key, got[key])
+		}
+	}
+
+	for _, c := range changes {
+		if c.Key == "REMOTE_DELETE" && !c.Remove {
+			t.Errorf("expected REMOTE_DELETE to be removed from the env file")
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(map[string]string{"A": "1", "B": "2"})
+	b := Fingerprint(map[string]string{"B": "2", "A": "1"})
+	c := Fingerprint(map[string]string{"A": "1", "B": "3"})
+
+	if a != b {
+		t.Errorf("expected fingerprint to ignore map order")
+	}
+	if a == c {
+		t.Errorf("expected fingerprint to change with a value")
+	}
+}
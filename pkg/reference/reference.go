@@ -0,0 +1,167 @@
+// Package reference resolves references to other parameters written inside
+// parameter values, such as {{ime:/shared/prod/DATABASE_URL}} or
+// {{ime:project/env/KEY}}.
+package reference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var refPattern = regexp.MustCompile(`\{\{\s*ime:([^{}\s]*)\s*\}\}`)
+
+// ErrNotFound is returned by a Lookup when the referenced parameter does not
+// exist.
+var ErrNotFound = errors.New("parameter not found")
+
+// ErrCycle is returned when references lead back to themselves.
+var ErrCycle = errors.New("reference cycle")
+
+// Ref is a reference to another parameter. Name is set for a reference by
+// absolute name, Project, Env and Key for one by project, environment and key.
+type Ref struct {
+	Name    string
+	Project string
+	Env     string
+	Key     string
+}
+
+func (r Ref) String() string {
+	if r.Name != "" {
+		return "{{ime:" + r.Name + "}}"
+	}
+	return fmt.Sprintf("{{ime:%s/%s/%s}}", r.Project, r.Env, r.Key)
+}
+
+// Parse parses the target of a reference, the text between "ime:" and the
+// closing braces.
+func Parse(target string) (Ref, error) {
+	if strings.HasPrefix(target, "/") {
+		if strings.HasSuffix(target, "/") || strings.Contains(target, "//") {
+			return Ref{}, fmt.Errorf("invalid reference {{ime:%s}}, expected a parameter name such as /shared/prod/KEY", target)
+		}
+		return Ref{Name: target}, nil
+	}
+
+	parts := strings.Split(target, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return Ref{}, fmt.Errorf("invalid reference {{ime:%s}}, expected /path/to/KEY or project/env/KEY", target)
+	}
+	return Ref{Project: parts[0], Env: parts[1], Key: parts[2]}, nil
+}
+
+// Contains reports whether value holds a reference.
+func Contains(value string) bool {
+	return refPattern.MatchString(value)
+}
+
+// Lookup returns the stored value of the parameter ref points at, or an error
+// wrapping ErrNotFound when there is none.
+type Lookup func(ctx context.Context, ref Ref) (string, error)
+
+// Resolver replaces references with the values they point at. Referenced
+// values may hold references themselves, which are resolved in turn. Every
+// parameter is looked up at most once.
+type Resolver struct {
+	lookup   Lookup
+	resolved map[Ref]string
+}
+
+func NewResolver(lookup Lookup) *Resolver {
+	return &Resolver{
+		lookup:   lookup,
+		resolved: make(map[Ref]string),
+	}
+}
+
+// Resolve returns value with every reference replaced.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	return r.resolve(ctx, value, nil)
+}
+
+// ResolveAll returns params with the references in every value replaced. The
+// error names the key whose value could not be resolved.
+func (r *Resolver) ResolveAll(ctx context.Context, params map[string]string) (map[string]string, error) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	resolved := make(map[string]string, len(params))
+	for _, k := range keys {
+		value, err := r.Resolve(ctx, params[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		resolved[k] = value
+	}
+	return resolved, nil
+}
+
+// resolve replaces the references in value. chain holds the references being
+// resolved that led to value, to detect cycles.
+func (r *Resolver) resolve(ctx context.Context, value string, chain []Ref) (string, error) {
+	matches := refPattern.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		ref, err := Parse(value[m[2]:m[3]])
+		if err != nil {
+			return "", err
+		}
+
+		target, err := r.resolveRef(ctx, ref, chain)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(value[last:m[0]])
+		b.WriteString(target)
+		last = m[1]
+	}
+	b.WriteString(value[last:])
+	return b.String(), nil
+}
+
+func (r *Resolver) resolveRef(ctx context.Context, ref Ref, chain []Ref) (string, error) {
+	for i, seen := range chain {
+		if seen == ref {
+			return "", fmt.Errorf("%w: %s", ErrCycle, formatChain(append(chain[i:], ref)))
+		}
+	}
+	if value, ok := r.resolved[ref]; ok {
+		return value, nil
+	}
+
+	raw, err := r.lookup(ctx, ref)
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("dangling reference %s: %w", ref, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s: %w", ref, err)
+	}
+
+	value, err := r.resolve(ctx, raw, append(chain[:len(chain):len(chain)], ref))
+	if err != nil {
+		return "", err
+	}
+	r.resolved[ref] = value
+	return value, nil
+}
+
+func formatChain(chain []Ref) string {
+	names := make([]string, len(chain))
+	for i, ref := range chain {
+		names[i] = ref.String()
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,109 @@
+package reference
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mapLookup looks references up in values, keyed by their String form.
+func mapLookup(values map[string]string, calls map[string]int) Lookup {
+	return func(ctx context.Context, ref Ref) (string, error) {
+		calls[ref.String()]++
+		value, ok := values[ref.String()]
+		if !ok {
+			return "", ErrNotFound
+		}
+		return value, nil
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		target      string
+		expected    Ref
+		expectError bool
+	}{
+		{"/shared/prod/DATABASE_URL", Ref{Name: "/shared/prod/DATABASE_URL"}, false},
+		{"billing/prod/API_KEY", Ref{Project: "billing", Env: "prod", Key: "API_KEY"}, false},
+		{"billing/API_KEY", Ref{}, true},
+		{"billing//API_KEY", Ref{}, true},
+		{"/shared/prod/", Ref{}, true},
+	}
+
+	for _, tt := range tests {
+		ref, err := Parse(tt.target)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("expected error for %s, but got none", tt.target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", tt.target, err)
+		} else if ref != tt.expected {
+			t.Errorf("expected %+v, but got %+v", tt.expected, ref)
+		}
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	calls := make(map[string]int)
+	r := NewResolver(mapLookup(map[string]string{
+		"{{ime:/shared/prod/DATABASE_URL}}": "postgres://{{ime:shared/prod/DB_USER}}@db",
+		"{{ime:shared/prod/DB_USER}}":       "app",
+	}, calls))
+
+	resolved, err := r.ResolveAll(context.Background(), map[string]string{
+		"DATABASE_URL": "{{ime:/shared/prod/DATABASE_URL}}",
+		"REPLICA_URL":  "{{ ime:/shared/prod/DATABASE_URL }}?replica=1",
+		"PLAIN":        "no {{references}} here",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"DATABASE_URL": "postgres://app@db",
+		"REPLICA_URL":  "postgres://app@db?replica=1",
+		"PLAIN":        "no {{references}} here",
+	}
+	for k, v := range expected {
+		if resolved[k] != v {
+			t.Errorf("expected %s=%s, but got %s", k, v, resolved[k])
+		}
+	}
+	if calls["{{ime:/shared/prod/DATABASE_URL}}"] != 1 {
+		t.Errorf("expected a reference used twice to be looked up once, but got %d lookups", calls["{{ime:/shared/prod/DATABASE_URL}}"])
+	}
+}
+
+func TestResolveCycle(t *testing.T) {
+	r := NewResolver(mapLookup(map[string]string{
+		"{{ime:app/dev/A}}": "{{ime:app/dev/B}}",
+		"{{ime:app/dev/B}}": "x-{{ime:app/dev/A}}",
+	}, make(map[string]int)))
+
+	_, err := r.Resolve(context.Background(), "{{ime:app/dev/A}}")
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("expected a reference cycle, but got %v", err)
+	}
+	if !strings.Contains(err.Error(), "{{ime:app/dev/A}} -> {{ime:app/dev/B}} -> {{ime:app/dev/A}}") {
+		t.Errorf("expected the error to show the cycle, but got %v", err)
+	}
+}
+
+func TestResolveDangling(t *testing.T) {
+	r := NewResolver(mapLookup(map[string]string{
+		"{{ime:/shared/prod/DATABASE_URL}}": "{{ime:/shared/prod/RETIRED}}",
+	}, make(map[string]int)))
+
+	_, err := r.ResolveAll(context.Background(), map[string]string{"DATABASE_URL": "{{ime:/shared/prod/DATABASE_URL}}"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a dangling reference, but got %v", err)
+	}
+	if !strings.Contains(err.Error(), "DATABASE_URL: dangling reference {{ime:/shared/prod/RETIRED}}") {
+		t.Errorf("expected the error to name the key and the missing reference, but got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build unix
+
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunCommandExitCode(t *testing.T) {
+	tests := []struct {
+		args     []string
+		envars   []string
+		expected int
+	}{
+		{[]string{"/bin/sh", "-c", "exit 0"}, nil, 0},
+		{[]string{"/bin/sh", "-c", "exit 3"}, nil, 3},
+		{[]string{"/bin/sh", "-c", `exit "$IME_TEST_CODE"`}, []string{"IME_TEST_CODE=5"}, 5},
+		// Killed by SIGTERM, reported the way a shell does.
+		{[]string{"/bin/sh", "-c", "kill -TERM $$"}, nil, 128 + int(syscall.SIGTERM)},
+	}
+
+	for _, tt := range tests {
+		code, err := RunCommand(tt.args, tt.envars)
+		if err != nil {
+			t.Errorf("unexpected error running %v: %v", tt.args, err)
+		}
+		if code != tt.expected {
+			t.Errorf("expected exit code %d for %v, but got %d", tt.expected, tt.args, code)
+		}
+	}
+}
+
+func TestRunCommandNotFound(t *testing.T) {
+	code, err := RunCommand([]string{filepath.Join(t.TempDir(), "missing")}, nil)
+	if err == nil {
+		t.Errorf("expected error for a missing command, but got none")
+	}
+	if code != 127 {
+		t.Errorf("expected exit code 127, but got %d", code)
+	}
+}
+
+func TestRunCommandForwardsSignals(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+
+	// Signal ime once the child has set its trap; the child only exits 7 if
+	// the signal is passed on to it.
+	go func() {
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(ready); err == nil {
+				syscall.Kill(os.Getpid(), syscall.SIGTERM)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	script := `trap 'exit 7' TERM; touch "$IME_READY"; while :; do sleep 0.05; done`
+	code, err := RunCommand([]string{"/bin/sh", "-c", script}, []string{"IME_READY=" + ready})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 7 {
+		t.Errorf("expected the child to trap the forwarded SIGTERM and exit 7, but got %d", code)
+	}
+}
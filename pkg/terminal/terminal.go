@@ -7,6 +7,33 @@ import (
 	"strings"
 )
 
+const (
+	ProjectVar = "IME_PROJECT"
+	EnvVar     = "IME_ENV"
+)
+
+// Session describes the project environment a subshell is started for.
+type Session struct {
+	Project string
+	Env     string
+	// Prompt prefixes PS1 with a marker naming the session. Shells that set
+	// PS1 in their rc files will override it.
+	Prompt bool
+}
+
+// CurrentSession returns the session this process is running in, if any.
+func CurrentSession() (Session, bool) {
+	project, ok := os.LookupEnv(ProjectVar)
+	if !ok {
+		return Session{}, false
+	}
+	return Session{Project: project, Env: os.Getenv(EnvVar)}, true
+}
+
+func (s Session) Marker() string {
+	return fmt.Sprintf("(ime:%s/%s) ", s.Project, s.Env)
+}
+
 func GetEnvAsMap() map[string]string {
 	envMap := make(map[string]string)
 	for _, env := range os.Environ() {
@@ -18,13 +45,16 @@ func GetEnvAsMap() map[string]string {
 	return envMap
 }
 
-func StartSubshell(envars []string) {
+func StartSubshell(session Session, envars []string) {
 	// Define the subshell command (e.g., /bin/bash or /bin/sh)
 	env := GetEnvAsMap()
-	shell := env["SHELL"] //"/bin/bash"
+	shell := env["SHELL"]
+	if shell == "" {
+		shell = "/bin/sh"
+	}
 
 	// Create the command to start the subshell
-	fmt.Println("Session started with Project: project and Environment: env \ntype 'exit' to exit the session at any time")
+	fmt.Printf("Session started with Project: %s and Environment: %s \ntype 'exit' to exit the session at any time\n", session.Project, session.Env)
 	cmd := exec.Command(shell)
 
 	// Set up the new environment variables
@@ -33,6 +63,16 @@ func StartSubshell(envars []string) {
 	for _, envar := range envars {
 		newEnv = append(newEnv, envar)
 	}
+
+	newEnv = append(newEnv, ProjectVar+"="+session.Project, EnvVar+"="+session.Env)
+
+	if session.Prompt {
+		ps1, ok := env["PS1"]
+		if !ok {
+			ps1 = "$ "
+		}
+		newEnv = append(newEnv, "PS1="+session.Marker()+ps1)
+	}
 	cmd.Env = newEnv
 
 	// Redirect standard input, output, and error to the subshell